@@ -30,17 +30,43 @@ type ClusterConfigSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	Config models.ConfigModel `json:"config,omitempty"`
+
+	// Priority orders the ClusterConfigs when they are layered into the FullConfigs.
+	// ClusterConfigs are folded in ascending order of priority, so the one with the
+	// highest priority is applied last. Ties are broken by name.
+	// +optional
+	Priority int `json:"priority,omitempty"`
+
+	// RolloutStrategy controls how changes of the config are rolled out across the nodes.
+	// When several ClusterConfigs set one, the one with the highest priority is used.
+	// A rollout strategy set on a NodeConfig takes precedence.
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
 }
 
 // ClusterConfigStatus defines the observed state of ClusterConfig
 type ClusterConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the metadata.generation the status was computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the ClusterConfig state.
+	// Known condition types are Ready, Merged, Applied and Degraded.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // ClusterConfig is the Schema for the clusterconfigs API
 type ClusterConfig struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -0,0 +1,54 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+const (
+	// ConditionTypeReady is true when the config is merged, applied on every targeted node and nothing is degraded.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeMerged is true when the config is part of the merged config of the FullConfigs.
+	ConditionTypeMerged = "Merged"
+	// ConditionTypeApplied is true when the merged config is injected into the agents of all the targeted nodes.
+	ConditionTypeApplied = "Applied"
+	// ConditionTypeDegraded is true when some nodes don't get the config, e.g. because their agent rejected it.
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeConflict is true when the nodeSelector of a NodeConfig selects nodes that are
+	// selected by other NodeConfigs too. Such nodes don't get the config of any of them.
+	ConditionTypeConflict = "Conflict"
+	// ConditionTypeDrifted is true when the config applied on some nodes, as read back from their agents,
+	// differs from the merged config of the FullConfig.
+	ConditionTypeDrifted = "Drifted"
+)
+
+const (
+	ReasonReady                   = "Ready"
+	ReasonNotReady                = "NotReady"
+	ReasonMerged                  = "Merged"
+	ReasonMergePending            = "MergePending"
+	ReasonInvalidConfig           = "InvalidConfig"
+	ReasonFullConfigMissing       = "FullConfigMissing"
+	ReasonApplied                 = "Applied"
+	ReasonApplyPending            = "ApplyPending"
+	ReasonApplyFailed             = "ApplyFailed"
+	ReasonAgentUnavailable        = "AgentUnavailable"
+	ReasonRolledBack              = "RolledBack"
+	ReasonNoFullConfig            = "NoFullConfig"
+	ReasonAsExpected              = "AsExpected"
+	ReasonOverlappingNodeSelector = "OverlappingNodeSelector"
+	ReasonNoOverlap               = "NoOverlap"
+	ReasonDriftDetected           = "DriftDetected"
+	ReasonNoDrift                 = "NoDrift"
+)
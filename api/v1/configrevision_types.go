@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"github.com/plutocholia/ipruler-operator/internal/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FullConfigLabel labels the ConfigRevisions with the name of the FullConfig they belong to.
+	FullConfigLabel = "ipruler.pegah.tech/fullconfig"
+
+	// AuthorAnnotation on a FullConfig describes who made the last change of its spec.mergedConfig.
+	AuthorAnnotation = "ipruler.pegah.tech/author"
+
+	// RollbackAnnotation on a FullConfig requests to roll spec.mergedConfig back to the given revision.
+	RollbackAnnotation = "ipruler.pegah.tech/rollback-to-revision"
+
+	// RolledBackAnnotation on a FullConfig records the revision spec.mergedConfig has been rolled back to,
+	// until the ClusterConfigs or the NodeConfig change it again.
+	RolledBackAnnotation = "ipruler.pegah.tech/rolled-back-to-revision"
+)
+
+// ConfigRevisionSpec is an immutable snapshot of the merged config of a FullConfig.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type ConfigRevisionSpec struct {
+	// FullConfig is the name of the FullConfig the revision belongs to.
+	FullConfig string `json:"fullConfig"`
+
+	// Revision is the number of the revision, increasing with every change of the merged config.
+	Revision int64 `json:"revision"`
+
+	// ConfigHash is the hash of the config.
+	ConfigHash string `json:"configHash"`
+
+	// Author describes who made the change.
+	// +optional
+	Author string `json:"author,omitempty"`
+
+	// Config is the merged config of the FullConfig.
+	Config models.ConfigModel `json:"config,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="FullConfig",type=string,JSONPath=`.spec.fullConfig`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.spec.revision`
+// +kubebuilder:printcolumn:name="Hash",type=string,JSONPath=`.spec.configHash`
+// +kubebuilder:printcolumn:name="Author",type=string,JSONPath=`.spec.author`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// ConfigRevision is the Schema for the configrevisions API
+type ConfigRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ConfigRevisionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConfigRevisionList contains a list of ConfigRevision
+type ConfigRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ConfigRevision{}, &ConfigRevisionList{})
+}
@@ -33,6 +33,76 @@ type FullConfigSpec struct {
 	ClusterConfig models.ConfigModel `json:"clusterConfig,omitempty"`
 	NodeConfig    models.ConfigModel `json:"nodeConfig,omitempty"`
 	MergedConfig  models.ConfigModel `json:"mergedConfig,omitempty"`
+
+	// Exclude is the exclude block of the NodeConfig, it leaves entries of spec.clusterConfig out of spec.mergedConfig.
+	// +optional
+	Exclude *models.ExcludeModel `json:"exclude,omitempty"`
+
+	// ClusterRolloutStrategy is the rollout strategy of the ClusterConfigs.
+	// +optional
+	ClusterRolloutStrategy *RolloutStrategy `json:"clusterRolloutStrategy,omitempty"`
+
+	// NodeRolloutStrategy is the rollout strategy of the NodeConfig, it takes precedence over ClusterRolloutStrategy.
+	// +optional
+	NodeRolloutStrategy *RolloutStrategy `json:"nodeRolloutStrategy,omitempty"`
+
+	// DriftPolicy is the drift policy of the NodeConfig.
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// ResyncInterval is the resync interval of the NodeConfig.
+	// +optional
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+}
+
+// EffectiveRolloutStrategy returns the rollout strategy the merged config is rolled out with, if any.
+func (s *FullConfigSpec) EffectiveRolloutStrategy() *RolloutStrategy {
+	if s.NodeRolloutStrategy != nil {
+		return s.NodeRolloutStrategy
+	}
+	return s.ClusterRolloutStrategy
+}
+
+// NodeStatus describes the delivery of the merged config to the agent of a single node
+type NodeStatus struct {
+	// NodeName is the name of the node selected by the FullConfig.
+	NodeName string `json:"nodeName"`
+
+	// AgentPod is the ipruler-agent pod the config was sent to.
+	// +optional
+	AgentPod string `json:"agentPod,omitempty"`
+
+	// ConfigHash is the hash of the config that is applied on the node.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// LastAttemptTime is the last time the config was sent to the agent.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// Applied is true when the last attempt to inject the merged config into the agent succeeded.
+	Applied bool `json:"applied"`
+
+	// Attempts is the number of consecutive failed attempts to inject the merged config into the agent.
+	// +optional
+	Attempts int `json:"attempts,omitempty"`
+
+	// Message is a human readable description of the result of the last attempt.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastDriftCheckTime is the last time the config applied on the node was read back from the agent.
+	// +optional
+	LastDriftCheckTime *metav1.Time `json:"lastDriftCheckTime,omitempty"`
+
+	// Drifted is true when the config applied on the node differs from the merged config.
+	// +optional
+	Drifted bool `json:"drifted,omitempty"`
+
+	// Drift describes how the config applied on the node differs from the merged config,
+	// or why it couldn't be read back from the agent.
+	// +optional
+	Drift string `json:"drift,omitempty"`
 }
 
 // FullConfigStatus defines the observed state of FullConfig
@@ -41,11 +111,75 @@ type FullConfigStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	HasNodeConfig    bool `json:"hasNodeConfig"`
 	HasClusterConfig bool `json:"hasClusterConfig"`
+
+	// ClusterConfigs lists the ClusterConfigs folded into spec.clusterConfig, in the order they were applied.
+	// +optional
+	ClusterConfigs []string `json:"clusterConfigs,omitempty"`
+
+	// Overrides lists the entries of spec.clusterConfig replaced by an entry of spec.nodeConfig with the same identity,
+	// e.g. a route to the same destination in the same table.
+	// +optional
+	Overrides []string `json:"overrides,omitempty"`
+
+	// Excluded lists the entries of spec.clusterConfig left out of spec.mergedConfig by spec.exclude.
+	// +optional
+	Excluded []string `json:"excluded,omitempty"`
+
+	// Nodes describes the delivery of spec.mergedConfig to every node selected by the FullConfig.
+	// +listType=map
+	// +listMapKey=nodeName
+	// +optional
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+
+	// TargetNodes is the number of nodes the merged config has to be injected into.
+	TargetNodes int `json:"targetNodes"`
+
+	// AppliedNodes is the number of nodes that have the current merged config applied.
+	AppliedNodes int `json:"appliedNodes"`
+
+	// CurrentRevision is the number of the ConfigRevision holding spec.mergedConfig.
+	// +optional
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// LastKnownGoodConfig is the last merged config that was applied on every targeted node.
+	// Failed canaries are rolled back to it.
+	// +optional
+	LastKnownGoodConfig *models.ConfigModel `json:"lastKnownGoodConfig,omitempty"`
+
+	// LastKnownGoodHash is the hash of LastKnownGoodConfig.
+	// +optional
+	LastKnownGoodHash string `json:"lastKnownGoodHash,omitempty"`
+
+	// DriftedNodes is the number of nodes whose applied config drifted from the merged config.
+	// +optional
+	DriftedNodes int `json:"driftedNodes,omitempty"`
+
+	// Rollout tracks the progress of the rollout of spec.mergedConfig when a rollout strategy is set.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// ObservedGeneration is the metadata.generation the status was computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the FullConfig state.
+	// Known condition types are Ready, Merged, Applied and Degraded.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Applied",type=integer,JSONPath=`.status.appliedNodes`
+// +kubebuilder:printcolumn:name="Target",type=integer,JSONPath=`.status.targetNodes`
+// +kubebuilder:printcolumn:name="Drifted",type=integer,JSONPath=`.status.driftedNodes`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.status.currentRevision`
+// +kubebuilder:printcolumn:name="Rollout",type=string,JSONPath=`.status.rollout.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // FullConfig is the Schema for the fullconfigs API
 type FullConfig struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -29,17 +29,67 @@ type NodeConfigSpec struct {
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.nodeSelector is immutable and cannot be changed"
 	NodeSelector map[string]string  `json:"nodeSelector,omitempty"`
 	Config       models.ConfigModel `json:"config,omitempty"`
+
+	// Exclude leaves rules, routes and VLANs of the ClusterConfigs out of the merged config of the selected nodes,
+	// e.g. a rule through eth2 for nodes without eth2.
+	// +optional
+	Exclude *models.ExcludeModel `json:"exclude,omitempty"`
+
+	// RolloutStrategy controls how changes of the config are rolled out across the selected nodes.
+	// It takes precedence over the rollout strategy of the ClusterConfigs.
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// DriftPolicy decides what happens when the config applied on a node drifts from the merged config,
+	// e.g. because a rule was deleted by hand. Report only records the drift in the FullConfig status,
+	// Correct injects the merged config into the node again.
+	// +kubebuilder:validation:Enum=Report;Correct
+	// +kubebuilder:default=Report
+	// +optional
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// ResyncInterval is how often the merged config is injected into the selected nodes again, even if nothing changed.
+	// Defaults to the resync interval of the operator, zero disables the resync.
+	// +optional
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
 }
 
+// DriftPolicy decides what happens when the config applied on a node drifts from the merged config.
+type DriftPolicy string
+
+const (
+	// DriftPolicyReport records the drift in the status of the FullConfig.
+	DriftPolicyReport DriftPolicy = "Report"
+	// DriftPolicyCorrect records the drift and injects the merged config into the node again.
+	DriftPolicyCorrect DriftPolicy = "Correct"
+)
+
 // NodeConfigStatus defines the observed state of NodeConfig
 type NodeConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ConflictingNodes lists the nodes that are selected by this NodeConfig and by other NodeConfigs as well.
+	// +optional
+	ConflictingNodes []string `json:"conflictingNodes,omitempty"`
+
+	// ObservedGeneration is the metadata.generation the status was computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the NodeConfig state.
+	// Known condition types are Ready, Merged, Applied and Degraded, as well as Conflict.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // NodeConfig is the Schema for the nodeconfigs API
 type NodeConfig struct {
 	metav1.TypeMeta   `json:",inline"`
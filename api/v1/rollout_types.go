@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// RolloutStrategy describes how a config change is rolled out across the nodes of a FullConfig.
+// Without a rollout strategy the config is injected into every node at once.
+type RolloutStrategy struct {
+	// MaxUnavailable is the number of nodes, or the percentage of the targeted nodes, the new config
+	// is injected into at once. A batch is only started when fewer nodes than that are failing on the new config.
+	// Defaults to 1.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// PauseBetweenBatches is how long to wait after a batch before starting the next one.
+	// +optional
+	PauseBetweenBatches *metav1.Duration `json:"pauseBetweenBatches,omitempty"`
+
+	// HaltOnFailure stops the rollout as soon as a node fails to apply the new config.
+	// A halted rollout continues once the spec changes, e.g. when the config is fixed.
+	// +optional
+	HaltOnFailure bool `json:"haltOnFailure,omitempty"`
+
+	// Paused holds back the new config from the nodes that are not updated yet.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Canary rolls the new config out to a subset of the nodes first. The rest of the nodes only get it
+	// once all the canaries applied it, otherwise the canaries are rolled back to the last known-good config.
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+}
+
+// CanaryStrategy selects the nodes a new config is tried on before the rest of the nodes.
+// Only nodes with a ready agent pod are canaries, without a node selector or a percentage the first of them is the only one.
+type CanaryStrategy struct {
+	// NodeSelector selects the canary nodes by their labels. It takes precedence over Percentage.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Percentage of the targeted nodes, in the order of their names, used as canaries.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Percentage int `json:"percentage,omitempty"`
+
+	// HealthCheckPath is an HTTP path of the agent that has to respond with a 2xx status
+	// after the new config is injected, for a canary to be considered healthy.
+	// +optional
+	HealthCheckPath string `json:"healthCheckPath,omitempty"`
+}
+
+// RolloutPhase is the phase of the rollout of a config.
+type RolloutPhase string
+
+const (
+	// RolloutPhaseProgressing means the config is being injected into the nodes batch by batch.
+	RolloutPhaseProgressing RolloutPhase = "Progressing"
+	// RolloutPhasePaused means the rollout is paused by the rollout strategy.
+	RolloutPhasePaused RolloutPhase = "Paused"
+	// RolloutPhaseHalted means the rollout stopped because a node failed to apply the config.
+	RolloutPhaseHalted RolloutPhase = "Halted"
+	// RolloutPhaseCanary means the config is being tried on the canary nodes.
+	RolloutPhaseCanary RolloutPhase = "Canary"
+	// RolloutPhaseRolledBack means a canary failed and the canaries are rolled back to the last known-good config.
+	RolloutPhaseRolledBack RolloutPhase = "RolledBack"
+	// RolloutPhaseCompleted means every targeted node has the config applied.
+	RolloutPhaseCompleted RolloutPhase = "Completed"
+)
+
+// RolloutStatus describes the progress of the rollout of the merged config across the nodes.
+type RolloutStatus struct {
+	// ConfigHash is the hash of the config being rolled out.
+	ConfigHash string `json:"configHash"`
+
+	// Phase is the phase of the rollout.
+	Phase RolloutPhase `json:"phase"`
+
+	// UpdatedNodes is the number of targeted nodes that have the config applied.
+	UpdatedNodes int `json:"updatedNodes"`
+
+	// TotalNodes is the number of targeted nodes.
+	TotalNodes int `json:"totalNodes"`
+
+	// CanaryNodes lists the canary nodes of the rollout.
+	// +optional
+	CanaryNodes []string `json:"canaryNodes,omitempty"`
+
+	// LastBatchTime is the time the last batch of nodes was started.
+	// +optional
+	LastBatchTime *metav1.Time `json:"lastBatchTime,omitempty"`
+
+	// Message is a human readable description of the state of the rollout.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
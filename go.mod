@@ -15,6 +15,8 @@ require (
 	sigs.k8s.io/controller-runtime v0.18.2
 )
 
+require github.com/evanphx/json-patch v4.12.0+incompatible // indirect
+
 require (
 	github.com/Netflix/go-env v0.0.0-20220526054621-78278af1949d
 	github.com/beorn7/perks v1.0.1 // indirect
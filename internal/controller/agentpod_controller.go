@@ -2,7 +2,6 @@ package controller
 
 import (
 	"context"
-	"time"
 
 	"github.com/go-logr/logr"
 	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
@@ -79,32 +78,16 @@ func (r *AgentPodsReconciler) handleUpdateOrCreate(ctx context.Context, pod *cor
 		return ctrl.Result{}, err
 	}
 
-	// find the FullConfig corresponding to the pod
-	var matchedFullConfig *iprulerv1.FullConfig
+	// trigger every FullConfig corresponding to the pod for doing request stuff, the FullConfigs themselves
+	// refuse to inject into the node if it is selected by more than one of them
 	for _, fullConfig := range fullConfigList.Items {
-		fullConfigMatchTheNode := true
-		for fullConfigLabelKey, fullConfigLabelValue := range fullConfig.Spec.NodeSelector {
-			if node.GetLabels()[fullConfigLabelKey] != fullConfigLabelValue {
-				fullConfigMatchTheNode = false
-				break
-			}
-		}
-		if fullConfigMatchTheNode {
-			matchedFullConfig = &fullConfig
-		}
-	}
-
-	// trigger the FullConfig resource for doing request stuff
-	if matchedFullConfig != nil {
-		if matchedFullConfig.Annotations == nil {
-			matchedFullConfig.Annotations = map[string]string{}
+		if !nodeMatchesSelector(&node, fullConfig.Spec.NodeSelector) {
+			continue
 		}
-		matchedFullConfig.Annotations["lastUpdateTrigger"] = time.Now().Format(time.RFC3339)
-		if err := r.Client.Update(ctx, matchedFullConfig); err != nil {
+		if err := triggerFullConfig(ctx, r.Client, &fullConfig); err != nil {
 			r.Log.Error(err, "Failed to update FullConfig to trigger reconciliation")
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
 	}
 
 	return ctrl.Result{}, nil
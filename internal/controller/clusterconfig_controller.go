@@ -18,9 +18,14 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -46,16 +51,12 @@ type ClusterConfigReconciler struct {
 func (r *ClusterConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
-	// check if the request is empty due to watch over FullConfig crd in non-existence of any ClusterConfig
-	if req.NamespacedName.Name == "" && req.NamespacedName.Namespace == "" {
-		return ctrl.Result{}, nil
-	}
-
 	var clusterConfig iprulerv1.ClusterConfig
 	if err := r.Get(ctx, req.NamespacedName, &clusterConfig); err != nil {
 		if apierrors.IsNotFound(err) {
 			r.Log.Info("resource has been deleted", "namespace", req.Namespace, "name", req.Name)
-			return ctrl.Result{}, nil
+			// the remaining ClusterConfigs have to be folded again without the deleted one
+			return r.handleUpdateOrCreate(ctx)
 		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
@@ -69,17 +70,24 @@ func (r *ClusterConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, nil
 	}
 
-	if res, err := r.handleUpdateOrCreate(ctx, &clusterConfig); err != nil {
+	if res, err := r.handleUpdateOrCreate(ctx); err != nil {
 		return res, err
 	}
 
 	return ctrl.Result{}, nil
 }
 
-func (r *ClusterConfigReconciler) handleUpdateOrCreate(ctx context.Context, clusterConfig *iprulerv1.ClusterConfig) (ctrl.Result, error) {
+// handleUpdateOrCreate folds every ClusterConfig in the cluster into spec.clusterConfig of all the FullConfigs,
+// regardless of which ClusterConfig triggered the reconciliation.
+func (r *ClusterConfigReconciler) handleUpdateOrCreate(ctx context.Context) (ctrl.Result, error) {
+	clusterConfigList := &iprulerv1.ClusterConfigList{}
+	if err := r.Client.List(ctx, clusterConfigList); err != nil {
+		r.Log.Error(err, "Failed to List ClusterConfig")
+		return ctrl.Result{}, err
+	}
 
-	sharedFullConfig.ClusterConfigName = clusterConfig.Name
-	sharedFullConfig.ClusterConfigNamespace = clusterConfig.Namespace
+	clusterConfig, clusterConfigNames := foldClusterConfigs(clusterConfigList.Items)
+	rolloutStrategy := clusterRolloutStrategy(clusterConfigList.Items)
 
 	fullConfigList := &iprulerv1.FullConfigList{}
 	if err := r.Client.List(ctx, fullConfigList); err != nil {
@@ -88,14 +96,24 @@ func (r *ClusterConfigReconciler) handleUpdateOrCreate(ctx context.Context, clus
 	}
 
 	if len(fullConfigList.Items) == 0 {
+		if err := r.updateClusterConfigStatuses(ctx, clusterConfigList.Items, fullConfigList.Items); err != nil {
+			return ctrl.Result{}, err
+		}
 		return ctrl.Result{Requeue: true}, nil
 	}
 
 	// update ClusterConfig and MergedConfig Part
 	for _, fullConfig := range fullConfigList.Items {
-		if !reflect.DeepEqual(fullConfig.Spec.ClusterConfig, clusterConfig.Spec.Config) {
-			fullConfig.Spec.ClusterConfig = clusterConfig.Spec.Config
-			fullConfig.Spec.MergedConfig = models.MergeConfigModels(&clusterConfig.Spec.Config, &fullConfig.Spec.NodeConfig)
+		if !reflect.DeepEqual(fullConfig.Spec.ClusterConfig, clusterConfig) || !reflect.DeepEqual(fullConfig.Spec.ClusterRolloutStrategy, rolloutStrategy) {
+			fullConfig.Spec.ClusterConfig = clusterConfig
+			fullConfig.Spec.ClusterRolloutStrategy = rolloutStrategy
+			if mergedConfig, _, _, err := mergeFullConfigSpec(&fullConfig.Spec); err != nil {
+				// the merged config is kept until the NodeConfig is fixed, the FullConfig reports the error
+				r.Log.Error(err, "Failed to merge spec.clusterConfig and spec.nodeConfig", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
+			} else {
+				fullConfig.Spec.MergedConfig = mergedConfig
+			}
+			setChangeAuthor(&fullConfig, clusterConfigsAuthor(clusterConfigList.Items))
 
 			if err := r.Client.Update(ctx, &fullConfig); err != nil && apierrors.IsConflict(err) {
 				r.Log.Info("Conflict in resource when updating spec.clusterConfig and spec.mergeConfig, The given FullConfig is changed", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
@@ -113,7 +131,12 @@ func (r *ClusterConfigReconciler) handleUpdateOrCreate(ctx context.Context, clus
 
 	// update status
 	for _, fullConfig := range fullConfigList.Items {
-		fullConfig.Status.HasClusterConfig = true
+		hasClusterConfig := len(clusterConfigNames) > 0
+		if fullConfig.Status.HasClusterConfig == hasClusterConfig && reflect.DeepEqual(fullConfig.Status.ClusterConfigs, clusterConfigNames) {
+			continue
+		}
+		fullConfig.Status.HasClusterConfig = hasClusterConfig
+		fullConfig.Status.ClusterConfigs = clusterConfigNames
 
 		if err := r.Client.Status().Update(ctx, &fullConfig); err != nil && apierrors.IsConflict(err) {
 			r.Log.Info("Conflict in resource, the given FullConfig had been changed", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
@@ -126,9 +149,76 @@ func (r *ClusterConfigReconciler) handleUpdateOrCreate(ctx context.Context, clus
 		}
 	}
 
+	if err := r.updateClusterConfigStatuses(ctx, clusterConfigList.Items, fullConfigList.Items); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// updateClusterConfigStatuses derives the conditions of every ClusterConfig from the FullConfigs they are folded into.
+// It is called once all the FullConfigs have the folded cluster config in their spec.
+func (r *ClusterConfigReconciler) updateClusterConfigStatuses(ctx context.Context, clusterConfigs []iprulerv1.ClusterConfig, fullConfigs []iprulerv1.FullConfig) error {
+	var pendingFullConfigs, degradedFullConfigs []string
+	for _, fullConfig := range fullConfigs {
+		applied := meta.IsStatusConditionTrue(fullConfig.Status.Conditions, iprulerv1.ConditionTypeApplied)
+		if !applied || fullConfig.Status.ObservedGeneration != fullConfig.Generation {
+			pendingFullConfigs = append(pendingFullConfigs, fullConfig.Name)
+		}
+		if meta.IsStatusConditionTrue(fullConfig.Status.Conditions, iprulerv1.ConditionTypeDegraded) {
+			degradedFullConfigs = append(degradedFullConfigs, fullConfig.Name)
+		}
+	}
+
+	for _, clusterConfig := range clusterConfigs {
+		if !clusterConfig.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+		oldStatus := clusterConfig.Status.DeepCopy()
+		conditions := &clusterConfig.Status.Conditions
+		generation := clusterConfig.Generation
+
+		if len(fullConfigs) == 0 {
+			setCondition(conditions, iprulerv1.ConditionTypeMerged, false, iprulerv1.ReasonNoFullConfig, "There is no FullConfig to merge the config into", generation)
+		} else {
+			setCondition(conditions, iprulerv1.ConditionTypeMerged, true, iprulerv1.ReasonMerged,
+				fmt.Sprintf("The config is merged into %d FullConfigs", len(fullConfigs)), generation)
+		}
+
+		if len(pendingFullConfigs) > 0 {
+			setCondition(conditions, iprulerv1.ConditionTypeApplied, false, iprulerv1.ReasonApplyPending,
+				fmt.Sprintf("FullConfigs %s are not applied on all of their nodes yet", strings.Join(pendingFullConfigs, ", ")), generation)
+		} else {
+			setCondition(conditions, iprulerv1.ConditionTypeApplied, true, iprulerv1.ReasonApplied, "Every FullConfig is applied on all of its nodes", generation)
+		}
+
+		if len(degradedFullConfigs) > 0 {
+			setCondition(conditions, iprulerv1.ConditionTypeDegraded, true, iprulerv1.ReasonApplyFailed,
+				fmt.Sprintf("FullConfigs %s are degraded", strings.Join(degradedFullConfigs, ", ")), generation)
+		} else {
+			setCondition(conditions, iprulerv1.ConditionTypeDegraded, false, iprulerv1.ReasonAsExpected, "No FullConfig is degraded", generation)
+		}
+
+		setReadyCondition(conditions, generation)
+		clusterConfig.Status.ObservedGeneration = generation
+
+		if reflect.DeepEqual(oldStatus, &clusterConfig.Status) {
+			continue
+		}
+		if err := r.Client.Status().Update(ctx, &clusterConfig); err != nil && apierrors.IsConflict(err) {
+			r.Log.Info("Conflict in resource, the given ClusterConfig had been changed", "Name", clusterConfig.Name)
+			return err
+		} else if err != nil {
+			r.Log.Error(err, "Failed to update ClusterConfig status", "Name", clusterConfig.Name)
+			return err
+		} else {
+			r.Log.Info("Updated ClusterConfig status", "Name", clusterConfig.Name)
+		}
+	}
+
+	return nil
+}
+
 func (r *ClusterConfigReconciler) handleDeletion(ctx context.Context, clusterConfig *iprulerv1.ClusterConfig) (ctrl.Result, error) {
 
 	return ctrl.Result{}, nil
@@ -146,15 +236,82 @@ func (r *ClusterConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 }
 
 func (r *ClusterConfigReconciler) findObjectsForFullConfig(ctx context.Context, fullConfig client.Object) []ctrl.Request {
+	clusterConfigList := &iprulerv1.ClusterConfigList{}
+	if err := r.Client.List(ctx, clusterConfigList); err != nil {
+		r.Log.Error(err, "Failed to List ClusterConfig")
+		return nil
+	}
+
+	// every reconciliation folds all the ClusterConfigs, so a single request is enough
+	if len(clusterConfigList.Items) == 0 {
+		return nil
+	}
 
-	requests := []ctrl.Request{
+	return []ctrl.Request{
 		{
 			NamespacedName: types.NamespacedName{
-				Name:      sharedFullConfig.ClusterConfigName,
-				Namespace: sharedFullConfig.ClusterConfigNamespace,
+				Name:      clusterConfigList.Items[0].Name,
+				Namespace: clusterConfigList.Items[0].Namespace,
 			},
 		},
 	}
+}
+
+// foldClusterConfigs layers the given ClusterConfigs in ascending order of priority (ties broken by name)
+// and returns the folded config along with the names of the contributing ClusterConfigs in that order.
+// ClusterConfigs that are being deleted are left out.
+func foldClusterConfigs(clusterConfigs []iprulerv1.ClusterConfig) (models.ConfigModel, []string) {
+	var folded models.ConfigModel
+	var names []string
+
+	for _, clusterConfig := range sortClusterConfigs(clusterConfigs) {
+		// a ClusterConfig with a higher priority overrides the entries of the ones before it
+		folded, _ = models.MergeConfigModels(&folded, &clusterConfig.Spec.Config)
+		names = append(names, clusterConfig.Name)
+	}
 
-	return requests
+	return folded, names
+}
+
+// clusterRolloutStrategy returns the rollout strategy of the ClusterConfig with the highest priority that sets one.
+func clusterRolloutStrategy(clusterConfigs []iprulerv1.ClusterConfig) *iprulerv1.RolloutStrategy {
+	var strategy *iprulerv1.RolloutStrategy
+	for _, clusterConfig := range sortClusterConfigs(clusterConfigs) {
+		if clusterConfig.Spec.RolloutStrategy != nil {
+			strategy = clusterConfig.Spec.RolloutStrategy
+		}
+	}
+	return strategy
+}
+
+// clusterConfigsAuthor describes the last change among the ClusterConfigs.
+func clusterConfigsAuthor(clusterConfigs []iprulerv1.ClusterConfig) string {
+	var author string
+	var last time.Time
+	for _, clusterConfig := range clusterConfigs {
+		if _, changedAt := lastChange(&clusterConfig); author == "" || changedAt.After(last) {
+			author, last = changeAuthor("ClusterConfig", &clusterConfig), changedAt
+		}
+	}
+	if author == "" {
+		return "deletion of the ClusterConfigs"
+	}
+	return author
+}
+
+// sortClusterConfigs returns the ClusterConfigs that are not being deleted in ascending order of priority, ties broken by name.
+func sortClusterConfigs(clusterConfigs []iprulerv1.ClusterConfig) []iprulerv1.ClusterConfig {
+	sorted := make([]iprulerv1.ClusterConfig, 0, len(clusterConfigs))
+	for _, clusterConfig := range clusterConfigs {
+		if clusterConfig.ObjectMeta.DeletionTimestamp.IsZero() {
+			sorted = append(sorted, clusterConfig)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Spec.Priority != sorted[j].Spec.Priority {
+			return sorted[i].Spec.Priority < sorted[j].Spec.Priority
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
 }
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+// setCondition sets the condition of the given type and reports whether anything has changed.
+func setCondition(conditions *[]metav1.Condition, conditionType string, status bool, reason, message string, generation int64) bool {
+	conditionStatus := metav1.ConditionFalse
+	if status {
+		conditionStatus = metav1.ConditionTrue
+	}
+	return meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// setReadyCondition derives the Ready condition from the Merged, Applied and Degraded conditions
+// and reports whether anything has changed.
+func setReadyCondition(conditions *[]metav1.Condition, generation int64) bool {
+	for _, conditionType := range []string{iprulerv1.ConditionTypeMerged, iprulerv1.ConditionTypeApplied} {
+		if condition := meta.FindStatusCondition(*conditions, conditionType); condition == nil || condition.Status != metav1.ConditionTrue {
+			message := conditionType + " condition is not true yet"
+			if condition != nil {
+				message = condition.Message
+			}
+			return setCondition(conditions, iprulerv1.ConditionTypeReady, false, iprulerv1.ReasonNotReady, message, generation)
+		}
+	}
+	if degraded := meta.FindStatusCondition(*conditions, iprulerv1.ConditionTypeDegraded); degraded != nil && degraded.Status == metav1.ConditionTrue {
+		return setCondition(conditions, iprulerv1.ConditionTypeReady, false, iprulerv1.ReasonNotReady, degraded.Message, generation)
+	}
+	return setCondition(conditions, iprulerv1.ConditionTypeReady, true, iprulerv1.ReasonReady, "The config is applied on every targeted node", generation)
+}
+
+// mirrorCondition copies the condition of the given type from the source conditions, e.g. those of a FullConfig,
+// into the given conditions. A missing source condition is mirrored as false with the pending reason.
+func mirrorCondition(conditions *[]metav1.Condition, source []metav1.Condition, conditionType, pendingReason string, generation int64) bool {
+	condition := meta.FindStatusCondition(source, conditionType)
+	if condition == nil {
+		return setCondition(conditions, conditionType, false, pendingReason, conditionType+" condition is not reported yet", generation)
+	}
+	return setCondition(conditions, conditionType, condition.Status == metav1.ConditionTrue, condition.Reason, condition.Message, generation)
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+func TestNodeConfigConflicts(t *testing.T) {
+	node := func(name string, labels map[string]string) client.Object {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	nodeConfig := func(name string, selector map[string]string, deleted bool) *iprulerv1.NodeConfig {
+		nodeConfig := &iprulerv1.NodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+			Spec:       iprulerv1.NodeConfigSpec{NodeSelector: selector},
+		}
+		if deleted {
+			nodeConfig.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			nodeConfig.Finalizers = []string{"test"}
+		}
+		return nodeConfig
+	}
+	nodes := []client.Object{
+		node("node-a", map[string]string{"role": "edge", "zone": "a"}),
+		node("node-b", map[string]string{"role": "edge", "zone": "b"}),
+		node("node-c", map[string]string{"role": "core", "zone": "a"}),
+	}
+
+	tests := []struct {
+		name            string
+		others          []client.Object
+		wantConflict    bool
+		wantNodes       []string
+		wantNodeConfigs []string
+		wantReason      string
+	}{
+		{
+			name:       "no other NodeConfig",
+			wantReason: iprulerv1.ReasonNoOverlap,
+		},
+		{
+			name:       "other NodeConfigs selecting other nodes",
+			others:     []client.Object{nodeConfig("core", map[string]string{"role": "core"}, false)},
+			wantReason: iprulerv1.ReasonNoOverlap,
+		},
+		{
+			name: "other NodeConfigs selecting the same nodes",
+			others: []client.Object{nodeConfig("zone-a", map[string]string{"zone": "a"}, false),
+				nodeConfig("all", nil, false), nodeConfig("core", map[string]string{"role": "core"}, false)},
+			wantConflict:    true,
+			wantNodes:       []string{"node-a", "node-b"},
+			wantNodeConfigs: []string{"all", "zone-a"},
+			wantReason:      iprulerv1.ReasonOverlappingNodeSelector,
+		},
+		{
+			name:       "NodeConfigs being deleted",
+			others:     []client.Object{nodeConfig("zone-a", map[string]string{"zone": "a"}, true)},
+			wantReason: iprulerv1.ReasonNoOverlap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			edge := nodeConfig("edge", map[string]string{"role": "edge"}, false)
+			objects := append(append([]client.Object{edge}, nodes...), tt.others...)
+			r := &NodeConfigReconciler{Client: newTestClient(t, objects...), Log: ctrl.Log.WithName("test")}
+
+			conflictingNodes, conflictingNodeConfigs, err := r.findConflicts(ctx, edge)
+			if err != nil {
+				t.Fatalf("findConflicts() error = %v", err)
+			}
+			if !slices.Equal(conflictingNodes, tt.wantNodes) || !slices.Equal(conflictingNodeConfigs, tt.wantNodeConfigs) {
+				t.Errorf("findConflicts() = %v, %v, want %v, %v", conflictingNodes, conflictingNodeConfigs, tt.wantNodes, tt.wantNodeConfigs)
+			}
+
+			if err := r.updateStatus(ctx, edge); err != nil {
+				t.Fatalf("updateStatus() error = %v", err)
+			}
+			updated := &iprulerv1.NodeConfig{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(edge), updated); err != nil {
+				t.Fatalf("failed to get the NodeConfig: %v", err)
+			}
+			if !slices.Equal(updated.Status.ConflictingNodes, tt.wantNodes) {
+				t.Errorf("status.conflictingNodes = %v, want %v", updated.Status.ConflictingNodes, tt.wantNodes)
+			}
+			conflict := meta.FindStatusCondition(updated.Status.Conditions, iprulerv1.ConditionTypeConflict)
+			if conflict == nil || (conflict.Status == metav1.ConditionTrue) != tt.wantConflict || conflict.Reason != tt.wantReason {
+				t.Errorf("Conflict condition = %+v, want %t with reason %s", conflict, tt.wantConflict, tt.wantReason)
+			}
+			degraded := meta.FindStatusCondition(updated.Status.Conditions, iprulerv1.ConditionTypeDegraded)
+			if degraded == nil || (degraded.Status == metav1.ConditionTrue) != tt.wantConflict {
+				t.Errorf("Degraded condition = %+v, want %t", degraded, tt.wantConflict)
+			}
+		})
+	}
+}
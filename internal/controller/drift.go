@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// checkDrift reads the config back from the agents of the nodes that have the merged config with the given hash applied,
+// once every DriftCheckInterval, and records in their status whether it drifted from the merged config.
+// Nodes the config is about to be injected into anyway (candidates) are skipped.
+// It returns the drifted nodes the merged config has to be injected into again according to the drift policy,
+// and how long to wait before the next check, or zero if there is nothing to check.
+func checkDrift(ctx context.Context, fullConfig *iprulerv1.FullConfig, targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus, candidates []int, hash string, now time.Time) ([]int, time.Duration) {
+	interval := envirnment.DriftCheckInterval
+	if interval <= 0 {
+		return nil, 0
+	}
+
+	var requeueAfter time.Duration
+	var due []int
+	var pods []*corev1.Pod
+	for i := range nodeStatuses {
+		nodeStatus := &nodeStatuses[i]
+		if targets[i].pod == nil || !nodeStatus.Applied || nodeStatus.ConfigHash != hash || slices.Contains(candidates, i) {
+			continue
+		}
+		if nodeStatus.LastDriftCheckTime != nil {
+			if nextCheck := nodeStatus.LastDriftCheckTime.Add(interval); now.Before(nextCheck) {
+				requeueAfter = minRequeueAfter(requeueAfter, nextCheck.Sub(now))
+				continue
+			}
+		}
+		due = append(due, i)
+		pods = append(pods, targets[i].pod)
+	}
+	if len(due) == 0 {
+		return nil, requeueAfter
+	}
+
+	var drifted []int
+	states, errs := globalAgentManager.FetchStates(ctx, pods)
+	for j, i := range due {
+		nodeStatus := &nodeStatuses[i]
+		nodeStatus.LastDriftCheckTime = &metav1.Time{Time: now}
+		if errors.Is(errs[j], errStateUnsupported) {
+			// older agents can't tell what is applied, which is neither a drift nor a failure
+			nodeStatus.Drifted = false
+			nodeStatus.Drift = "The agent doesn't support reading back the applied config"
+			continue
+		}
+		if errs[j] != nil {
+			// the drift is unknown, the last known one is kept
+			nodeStatus.Drift = fmt.Sprintf("Failed to read back the applied config: %s", errs[j])
+			continue
+		}
+
+		desired, err := targets[i].renderConfig(&fullConfig.Spec.MergedConfig)
+		if err != nil {
+			nodeStatus.Drift = fmt.Sprintf("Failed to render the merged config for the node: %s", err)
+			continue
+		}
+		diff := models.DiffConfigModels(desired, states[j])
+		nodeStatus.Drifted = !diff.Empty()
+		nodeStatus.Drift = diff.String()
+		if nodeStatus.Drifted && fullConfig.Spec.DriftPolicy == iprulerv1.DriftPolicyCorrect {
+			drifted = append(drifted, i)
+		}
+	}
+
+	return drifted, minRequeueAfter(requeueAfter, interval)
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+func TestCheckDrift(t *testing.T) {
+	const hash = "new"
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// the test agent has vlan.100 applied
+	applied := models.ConfigModel{Vlans: []models.VlanModel{{Name: "vlan.100", Link: "eth0", ID: 100}}}
+	changed := models.ConfigModel{Vlans: []models.VlanModel{{Name: "vlan.200", Link: "eth0", ID: 200}}}
+
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		statePath   string
+		config      models.ConfigModel
+		policy      iprulerv1.DriftPolicy
+		wantChecked bool
+		wantDrifted bool
+		wantCorrect bool
+		wantDrift   string
+	}{
+		{name: "disabled", config: changed, statePath: "state"},
+		{name: "no drift", interval: time.Minute, statePath: "state", config: applied, wantChecked: true},
+		{name: "drift is reported", interval: time.Minute, statePath: "state", config: changed, policy: iprulerv1.DriftPolicyReport,
+			wantChecked: true, wantDrifted: true},
+		{name: "drift is corrected", interval: time.Minute, statePath: "state", config: changed, policy: iprulerv1.DriftPolicyCorrect,
+			wantChecked: true, wantDrifted: true, wantCorrect: true},
+		{name: "agent without the state API", interval: time.Minute, statePath: "unsupported", config: changed, policy: iprulerv1.DriftPolicyCorrect,
+			wantChecked: true, wantDrift: "The agent doesn't support reading back the applied config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval := envirnment.DriftCheckInterval
+			envirnment.DriftCheckInterval = tt.interval
+			defer func() { envirnment.DriftCheckInterval = interval }()
+			mgr := newTestAgent(t, 1)
+			mgr.StatePath = tt.statePath
+			setTestAgentManager(t, mgr)
+
+			targets := testTargets("node-a")
+			targets[0].pod.Status.PodIP = "127.0.0.1"
+			nodeStatuses := testNodeStatuses(targets, hash)
+			fullConfig := &iprulerv1.FullConfig{Spec: iprulerv1.FullConfigSpec{MergedConfig: tt.config, DriftPolicy: tt.policy}}
+
+			drifted, requeueAfter := checkDrift(context.Background(), fullConfig, targets, nodeStatuses, nil, hash, now)
+
+			nodeStatus := nodeStatuses[0]
+			if checked := nodeStatus.LastDriftCheckTime != nil; checked != tt.wantChecked {
+				t.Fatalf("node checked %t, want %t", checked, tt.wantChecked)
+			}
+			if requeueAfter != tt.interval {
+				t.Errorf("requeue after %v, want %v", requeueAfter, tt.interval)
+			}
+			if nodeStatus.Drifted != tt.wantDrifted {
+				t.Errorf("drifted = %t, want %t: %s", nodeStatus.Drifted, tt.wantDrifted, nodeStatus.Drift)
+			}
+			if tt.wantDrift != "" && nodeStatus.Drift != tt.wantDrift {
+				t.Errorf("drift = %q, want %q", nodeStatus.Drift, tt.wantDrift)
+			}
+			if corrected := slices.Equal(drifted, []int{0}); corrected != tt.wantCorrect {
+				t.Errorf("nodes to correct = %v, want node 0 %t", drifted, tt.wantCorrect)
+			}
+		})
+	}
+}
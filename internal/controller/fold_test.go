@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+func TestFoldClusterConfigs(t *testing.T) {
+	// every ClusterConfig sets the link of the same VLAN, so the last one folded wins
+	clusterConfig := func(name string, priority int, deleted bool) iprulerv1.ClusterConfig {
+		clusterConfig := iprulerv1.ClusterConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: iprulerv1.ClusterConfigSpec{
+				Priority: priority,
+				Config: models.ConfigModel{Vlans: []models.VlanModel{
+					{Name: "vlan.100", Link: name, ID: 100},
+					{Name: "vlan." + name, Link: "eth0", ID: 200},
+				}},
+			},
+		}
+		if deleted {
+			clusterConfig.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			clusterConfig.Finalizers = []string{"test"}
+		}
+		return clusterConfig
+	}
+
+	tests := []struct {
+		name           string
+		clusterConfigs []iprulerv1.ClusterConfig
+		wantNames      []string
+	}{
+		{name: "no ClusterConfigs"},
+		{
+			name:           "ascending order of priority",
+			clusterConfigs: []iprulerv1.ClusterConfig{clusterConfig("base", 10, false), clusterConfig("site", 20, false), clusterConfig("defaults", -5, false)},
+			wantNames:      []string{"defaults", "base", "site"},
+		},
+		{
+			name:           "ties broken by name",
+			clusterConfigs: []iprulerv1.ClusterConfig{clusterConfig("zone-b", 0, false), clusterConfig("zone-a", 0, false), clusterConfig("site", 1, false)},
+			wantNames:      []string{"zone-a", "zone-b", "site"},
+		},
+		{
+			name:           "ClusterConfigs being deleted are left out",
+			clusterConfigs: []iprulerv1.ClusterConfig{clusterConfig("base", 0, false), clusterConfig("site", 1, true)},
+			wantNames:      []string{"base"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded, names := foldClusterConfigs(tt.clusterConfigs)
+
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("folded ClusterConfigs = %v, want %v", names, tt.wantNames)
+			}
+			var links, vlans []string
+			for _, vlan := range folded.Vlans {
+				vlans = append(vlans, vlan.Name)
+				if vlan.Name == "vlan.100" {
+					links = append(links, vlan.Link)
+				}
+			}
+			for _, name := range tt.wantNames {
+				if !slices.Contains(vlans, "vlan."+name) {
+					t.Errorf("folded config %v is missing the VLAN of %s", vlans, name)
+				}
+			}
+			if len(tt.wantNames) > 0 && !slices.Equal(links, tt.wantNames[len(tt.wantNames)-1:]) {
+				t.Errorf("shared VLAN is on links %v, want the one of the highest priority %s", links, tt.wantNames[len(tt.wantNames)-1])
+			}
+			if len(vlans) != len(tt.wantNames)+min(len(tt.wantNames), 1) {
+				t.Errorf("folded config has VLANs %v, want one of every ClusterConfig and the shared one", vlans)
+			}
+		})
+	}
+}
+
+func TestClusterRolloutStrategy(t *testing.T) {
+	withStrategy := func(name string, priority int, strategy *iprulerv1.RolloutStrategy) iprulerv1.ClusterConfig {
+		return iprulerv1.ClusterConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       iprulerv1.ClusterConfigSpec{Priority: priority, RolloutStrategy: strategy},
+		}
+	}
+	paused := &iprulerv1.RolloutStrategy{Paused: true}
+	haltOnFailure := &iprulerv1.RolloutStrategy{HaltOnFailure: true}
+
+	clusterConfigs := []iprulerv1.ClusterConfig{withStrategy("site", 20, nil), withStrategy("base", 10, paused), withStrategy("zone", 10, haltOnFailure)}
+	if got := clusterRolloutStrategy(clusterConfigs); got != haltOnFailure {
+		t.Errorf("clusterRolloutStrategy() = %+v, want the one of the highest priority that sets one %+v", got, haltOnFailure)
+	}
+	if got := clusterRolloutStrategy(clusterConfigs[:1]); got != nil {
+		t.Errorf("clusterRolloutStrategy() = %+v, want none", got)
+	}
+}
+
+func TestUpdateClusterConfigStatuses(t *testing.T) {
+	applied := iprulerv1.FullConfig{ObjectMeta: metav1.ObjectMeta{Name: "edge"}}
+	applied.Status.Conditions = []metav1.Condition{{Type: iprulerv1.ConditionTypeApplied, Status: metav1.ConditionTrue}}
+
+	tests := []struct {
+		name        string
+		fullConfigs []iprulerv1.FullConfig
+		wantMerged  metav1.ConditionStatus
+		wantReason  string
+		wantReady   metav1.ConditionStatus
+	}{
+		{name: "no FullConfig", wantMerged: metav1.ConditionFalse, wantReason: iprulerv1.ReasonNoFullConfig, wantReady: metav1.ConditionFalse},
+		{name: "merged into a FullConfig", fullConfigs: []iprulerv1.FullConfig{applied},
+			wantMerged: metav1.ConditionTrue, wantReason: iprulerv1.ReasonMerged, wantReady: metav1.ConditionTrue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clusterConfig := iprulerv1.ClusterConfig{ObjectMeta: metav1.ObjectMeta{Name: "base", Generation: 1}}
+			c := newTestClient(t, &clusterConfig)
+			r := &ClusterConfigReconciler{Client: c, Scheme: c.Scheme(), Log: ctrl.Log.WithName("test")}
+
+			if err := r.updateClusterConfigStatuses(ctx, []iprulerv1.ClusterConfig{clusterConfig}, tt.fullConfigs); err != nil {
+				t.Fatalf("updateClusterConfigStatuses() error = %v", err)
+			}
+
+			updated := &iprulerv1.ClusterConfig{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(&clusterConfig), updated); err != nil {
+				t.Fatalf("failed to get the ClusterConfig: %v", err)
+			}
+			merged := meta.FindStatusCondition(updated.Status.Conditions, iprulerv1.ConditionTypeMerged)
+			if merged == nil || merged.Status != tt.wantMerged || merged.Reason != tt.wantReason {
+				t.Errorf("Merged condition = %+v, want %s with reason %s", merged, tt.wantMerged, tt.wantReason)
+			}
+			if ready := meta.FindStatusCondition(updated.Status.Conditions, iprulerv1.ConditionTypeReady); ready == nil || ready.Status != tt.wantReady {
+				t.Errorf("Ready condition = %+v, want %s", ready, tt.wantReady)
+			}
+		})
+	}
+}
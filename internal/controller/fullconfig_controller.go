@@ -18,12 +18,20 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -35,13 +43,17 @@ import (
 // FullConfigReconciler reconciles a FullConfig object
 type FullConfigReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
-	Log    logr.Logger
+	// APIReader reads the Secrets the configs refer to without caching them
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
+	Log       logr.Logger
 }
 
 // +kubebuilder:rbac:groups=ipruler.pegah.tech,resources=fullconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ipruler.pegah.tech,resources=fullconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=ipruler.pegah.tech,resources=fullconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=ipruler.pegah.tech,resources=configrevisions,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=get;list;watch
 func (r *FullConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
@@ -67,40 +79,389 @@ func (r *FullConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
-	if res, err := r.handleUpdateOrCreate(ctx, &fullConfig); err != nil {
-		return res, err
+	if _, ok := fullConfig.Annotations[iprulerv1.RollbackAnnotation]; ok {
+		// the update of the spec triggers another reconciliation
+		return ctrl.Result{}, r.handleRollback(ctx, &fullConfig)
 	}
 
-	return ctrl.Result{}, nil
+	// the result carries the backoff of the nodes whose injection has to be retried
+	return r.handleUpdateOrCreate(ctx, &fullConfig)
+}
+
+// agentTarget is a node selected by a FullConfig along with the ready agent pod running on it, if any.
+type agentTarget struct {
+	node corev1.Node
+	pod  *corev1.Pod
+}
+
+// renderConfig renders the per-node templates of the config, like the addresses, for the node of the target.
+// Only the annotations with the templateAnnotationPrefix are available to the templates.
+func (t *agentTarget) renderConfig(config *models.ConfigModel) (*models.ConfigModel, error) {
+	return models.RenderConfigModel(config, &models.NodeInfo{Name: t.node.Name, Labels: t.node.Labels, Annotations: templateAnnotations(&t.node)})
 }
 
 func (r *FullConfigReconciler) handleUpdateOrCreate(ctx context.Context, fullConfig *iprulerv1.FullConfig) (ctrl.Result, error) {
+	targets, err := r.findAgentTargets(ctx, fullConfig)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	hash, err := configHash(&fullConfig.Spec.MergedConfig)
+	if err != nil {
+		r.Log.Error(err, "Failed to hash the merged config", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
+		return ctrl.Result{}, err
+	}
+
+	// the validating webhook is optional, so the merged config is checked again before it reaches any agent
+	merge := mergeSpec(fullConfig)
+	if len(merge.invalid) > 0 {
+		r.Log.Info("The merged config is invalid, skipping the injection", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name,
+			"Errors", merge.invalid.ToAggregate().Error())
+		// the nodes keep the config they have, until a change of the spec triggers another reconciliation
+		return ctrl.Result{}, r.updateNodeStatuses(ctx, fullConfig, fullConfig.Status.Nodes, hash, merge)
+	}
+
+	if err := r.recordRevision(ctx, fullConfig, hash); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	previous := map[string]iprulerv1.NodeStatus{}
+	for _, nodeStatus := range fullConfig.Status.Nodes {
+		previous[nodeStatus.NodeName] = nodeStatus
+	}
+
+	// a change of the spec or a trigger (node or agent pod events) starts over the injection on every node
+	specChanged := fullConfig.Status.ObservedGeneration != fullConfig.Generation
+	triggeredAt := lastUpdateTrigger(fullConfig)
+
+	var requeueAfter time.Duration
+	var candidates []int
+	now := time.Now()
+	nodeStatuses := make([]iprulerv1.NodeStatus, 0, len(targets))
+	for _, target := range targets {
+		nodeStatus := previous[target.node.Name]
+		nodeStatus.NodeName = target.node.Name
+		if target.pod == nil {
+			nodeStatus.AgentPod = ""
+			nodeStatus.Applied = false
+			nodeStatus.Message = "There is no ready agent pod on the node"
+			nodeStatuses = append(nodeStatuses, nodeStatus)
+			continue
+		}
+
+		if injectionRestarted(&nodeStatus, target.pod, specChanged, triggeredAt) {
+			nodeStatus.Attempts = 0
+		} else if due, after := injectionDue(&nodeStatus, hash, now); !due {
+			requeueAfter = minRequeueAfter(requeueAfter, after)
+			nodeStatuses = append(nodeStatuses, nodeStatus)
+			continue
+		}
+
+		candidates = append(candidates, len(nodeStatuses))
+		nodeStatuses = append(nodeStatuses, nodeStatus)
+	}
+
+	// the merged config is injected into the nodes again every resync interval
+	resynced, resyncRequeueAfter := resyncNodes(fullConfig, targets, nodeStatuses, candidates, hash, now)
+	requeueAfter = minRequeueAfter(requeueAfter, resyncRequeueAfter)
+	if len(resynced) > 0 {
+		r.Log.Info("Resyncing the merged config", "Name", fullConfig.Name, "Nodes", len(resynced))
+		candidates = append(candidates, resynced...)
+		sort.Ints(candidates)
+	}
+
+	// nodes whose applied config drifted from the merged config get it again if the drift policy says so
+	drifted, driftRequeueAfter := checkDrift(ctx, fullConfig, targets, nodeStatuses, candidates, hash, now)
+	requeueAfter = minRequeueAfter(requeueAfter, driftRequeueAfter)
+	if len(drifted) > 0 {
+		r.Log.Info("Correcting the drift of the applied config", "Name", fullConfig.Name, "Nodes", len(drifted))
+		candidates = append(candidates, drifted...)
+		sort.Ints(candidates)
+	}
+
+	// the rollout strategy may hold back the new config from some of the nodes, or roll failed canaries back
+	planned, rolloutRequeueAfter := planRollout(fullConfig, targets, nodeStatuses, candidates, hash, specChanged, now)
+	requeueAfter = minRequeueAfter(requeueAfter, rolloutRequeueAfter)
+
+	injections := make([]InjectRequest, 0, len(planned))
+	injected := make([]plannedInjection, 0, len(planned))
+	for _, p := range planned {
+		nodeStatus := &nodeStatuses[p.node]
+		nodeStatus.AgentPod = targets[p.node].pod.Name
+		nodeStatus.LastAttemptTime = &metav1.Time{Time: now}
+		config, err := targets[p.node].renderConfig(p.config)
+		if err != nil {
+			// rendering it again doesn't help until the node changes, which starts over the injection anyway
+			nodeStatus.Applied = false
+			nodeStatus.Attempts = globalAgentManager.MaxAttempts
+			nodeStatus.Message = fmt.Sprintf("Failed to render the config for the node: %s", err)
+			continue
+		}
+		// the Secret may show up later, so it's retried like a failed injection
+		if err := r.resolveSecrets(ctx, config); err != nil {
+			requeueAfter = minRequeueAfter(requeueAfter, recordInjectionFailure(nodeStatus, err))
+			continue
+		}
+		injected = append(injected, p)
+		injections = append(injections, InjectRequest{Pod: targets[p.node].pod, Config: config, HealthCheckPath: p.healthCheckPath})
+	}
+
+	// inject into the agents concurrently so that a hung agent doesn't hold up the others
+	for i, err := range globalAgentManager.InjectConfigs(ctx, injections) {
+		nodeStatus := &nodeStatuses[injected[i].node]
+		if err != nil {
+			requeueAfter = minRequeueAfter(requeueAfter, recordInjectionFailure(nodeStatus, err))
+		} else {
+			nodeStatus.Applied = true
+			nodeStatus.Attempts = 0
+			nodeStatus.ConfigHash = injected[i].hash
+			nodeStatus.Drifted = false
+			nodeStatus.Drift = ""
+			nodeStatus.Message = "Config injected"
+			if injected[i].rollback {
+				nodeStatus.Message = "Rolled back to the last known-good config"
+			}
+		}
+	}
+
+	requeueAfter = minRequeueAfter(requeueAfter, updateRolloutStatus(fullConfig, nodeStatuses, hash))
+	if err := r.updateNodeStatuses(ctx, fullConfig, nodeStatuses, hash, merge); err != nil {
+		return ctrl.Result{}, err
+	}
+	if requeueAfter > 0 {
+		r.Log.Info("Requeue to retry failed nodes, continue the rollout, check the drift or resync", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name, "RequeueAfter", requeueAfter)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// validateMergedConfig checks the merged config the same way the webhook checks the ClusterConfigs and NodeConfigs.
+func validateMergedConfig(config *models.ConfigModel) field.ErrorList {
+	return models.ValidateConfigModel(config, field.NewPath("spec", "mergedConfig"))
+}
+
+// specMerge is the outcome of merging the spec of the FullConfig and of validating the merged config it holds,
+// computed once per reconciliation for the injection, the status and the conditions.
+type specMerge struct {
+	config    models.ConfigModel
+	overrides []string
+	excluded  []string
+	err       error
+	invalid   field.ErrorList
+}
+
+// mergeSpec merges the cluster and node configs of the FullConfig and validates its merged config.
+func mergeSpec(fullConfig *iprulerv1.FullConfig) *specMerge {
+	merge := &specMerge{invalid: validateMergedConfig(&fullConfig.Spec.MergedConfig)}
+	merge.config, merge.overrides, merge.excluded, merge.err = mergeFullConfigSpec(&fullConfig.Spec)
+	return merge
+}
+
+// recordInjectionFailure records a failed attempt to inject the config into the node.
+// It returns the backoff before the next attempt, or zero when giving up.
+func recordInjectionFailure(nodeStatus *iprulerv1.NodeStatus, err error) time.Duration {
+	nodeStatus.Applied = false
+	nodeStatus.Attempts++
+	if nodeStatus.Attempts >= globalAgentManager.MaxAttempts {
+		nodeStatus.Message = fmt.Sprintf("Failed to inject the config, giving up after %d attempts: %s", nodeStatus.Attempts, err)
+		return 0
+	}
+	backoff := globalAgentManager.Backoff(nodeStatus.Attempts)
+	nodeStatus.Message = fmt.Sprintf("Failed to inject the config (attempt %d/%d), retrying in %s: %s",
+		nodeStatus.Attempts, globalAgentManager.MaxAttempts, backoff, err)
+	return backoff
+}
+
+// injectionRestarted reports whether the injection into the node starts over, along with its backoff, because the spec
+// has changed, e.g. a new merged config, the node was triggered since the last attempt, or its agent pod is a new one.
+func injectionRestarted(nodeStatus *iprulerv1.NodeStatus, pod *corev1.Pod, specChanged bool, triggeredAt time.Time) bool {
+	return specChanged || nodeStatus.LastAttemptTime == nil || nodeStatus.AgentPod != pod.Name ||
+		!triggeredAt.Before(nodeStatus.LastAttemptTime.Time)
+}
+
+// injectionDue reports whether the config has to be injected into the node again without any new trigger.
+// If it's not due yet, it returns how long to wait for the next retry, or zero if there is nothing to retry.
+func injectionDue(nodeStatus *iprulerv1.NodeStatus, hash string, now time.Time) (bool, time.Duration) {
+	if nodeStatus.Applied {
+		return nodeStatus.ConfigHash != hash, 0
+	}
+	if nodeStatus.Attempts >= globalAgentManager.MaxAttempts {
+		return false, 0
+	}
+	nextAttempt := nodeStatus.LastAttemptTime.Add(globalAgentManager.Backoff(nodeStatus.Attempts))
+	if !now.Before(nextAttempt) {
+		return true, 0
+	}
+	return false, nextAttempt.Sub(now)
+}
+
+// lastUpdateTrigger returns the time the FullConfig was last triggered by a node or an agent pod event.
+func lastUpdateTrigger(fullConfig *iprulerv1.FullConfig) time.Time {
+	triggeredAt, err := time.Parse(time.RFC3339, fullConfig.Annotations["lastUpdateTrigger"])
+	if err != nil {
+		return time.Time{}
+	}
+	return triggeredAt
+}
+
+// minRequeueAfter returns the shorter of the two durations, where zero means no requeue.
+func minRequeueAfter(a, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// findAgentTargets returns the nodes the config of the FullConfig has to be injected into, sorted by name.
+// A node selected by more than one FullConfig doesn't get any of them until the conflict is resolved.
+func (r *FullConfigReconciler) findAgentTargets(ctx context.Context, fullConfig *iprulerv1.FullConfig) ([]agentTarget, error) {
 	podList := &corev1.PodList{}
 	if err := r.List(ctx, podList, client.MatchingLabels{globalAgentManager.AppLabelKey: globalAgentManager.AppLabelValue}, client.InNamespace(globalAgentManager.Namespace)); err != nil {
 		r.Log.Error(err, "Failed to get the pods list")
-		return ctrl.Result{}, err
+		return nil, err
 	}
-	for _, pod := range podList.Items {
-		if PodIsReady(&pod) {
-			var node corev1.Node
-			if err := r.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, &node); err != nil {
-				r.Log.Error(err, "message", "Failed to get Node for Pod", "Pod", pod.Name)
-				return ctrl.Result{Requeue: true}, err
-			}
-			labelMatch := true
-			nodeLabels := node.GetLabels()
-			for key, value := range fullConfig.Spec.NodeSelector {
-				if nodeLabels[key] != value {
-					labelMatch = false
-					break
-				}
-			}
-			if labelMatch {
-				globalAgentManager.InjectConfig(&pod, &fullConfig.Spec.MergedConfig)
-			}
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		r.Log.Error(err, "Failed to List Node")
+		return nil, err
+	}
+	fullConfigList := &iprulerv1.FullConfigList{}
+	if err := r.List(ctx, fullConfigList); err != nil {
+		r.Log.Error(err, "Failed to List FullConfig")
+		return nil, err
+	}
+
+	readyPods := map[string]*corev1.Pod{}
+	for i := range podList.Items {
+		if PodIsReady(&podList.Items[i]) {
+			readyPods[podList.Items[i].Spec.NodeName] = &podList.Items[i]
 		}
 	}
-	return ctrl.Result{}, nil
+
+	var targets []agentTarget
+	for _, node := range nodeList.Items {
+		if !nodeMatchesSelector(&node, fullConfig.Spec.NodeSelector) {
+			continue
+		}
+		if others := overlappingFullConfigs(&node, fullConfig, fullConfigList.Items); len(others) > 0 {
+			r.Log.Info("Node is selected by other FullConfigs as well, skipping the injection", "Node", node.Name, "FullConfigs", others)
+			continue
+		}
+		targets = append(targets, agentTarget{node: node, pod: readyPods[node.Name]})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].node.Name < targets[j].node.Name
+	})
+
+	return targets, nil
+}
+
+// updateNodeStatuses records the per-node delivery of the merged config with the given hash, and the outcome of the merge
+// of the spec, in the FullConfig status.
+func (r *FullConfigReconciler) updateNodeStatuses(ctx context.Context, fullConfig *iprulerv1.FullConfig, nodeStatuses []iprulerv1.NodeStatus, hash string, merge *specMerge) error {
+	appliedNodes, driftedNodes := 0, 0
+	for _, nodeStatus := range nodeStatuses {
+		if nodeStatus.Applied && nodeStatus.ConfigHash == hash {
+			appliedNodes++
+		}
+		if nodeStatus.Drifted {
+			driftedNodes++
+		}
+	}
+
+	fullConfig.Status.Nodes = nodeStatuses
+	fullConfig.Status.TargetNodes = len(nodeStatuses)
+	fullConfig.Status.AppliedNodes = appliedNodes
+	fullConfig.Status.DriftedNodes = driftedNodes
+	// a merge error is reported by the Merged condition
+	fullConfig.Status.Overrides, fullConfig.Status.Excluded = merge.overrides, merge.excluded
+	fullConfig.Status.ObservedGeneration = fullConfig.Generation
+	if len(nodeStatuses) > 0 && appliedNodes == len(nodeStatuses) && fullConfig.Status.LastKnownGoodHash != hash {
+		fullConfig.Status.LastKnownGoodConfig = fullConfig.Spec.MergedConfig.DeepCopy()
+		fullConfig.Status.LastKnownGoodHash = hash
+	}
+	r.setConditions(fullConfig, merge)
+
+	if err := r.Status().Update(ctx, fullConfig); err != nil && apierrors.IsConflict(err) {
+		r.Log.Info("Conflict in resource, the given FullConfig had been changed", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
+		return err
+	} else if err != nil {
+		r.Log.Error(err, "Failed to update FullConfig status", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
+		return err
+	}
+	r.Log.Info("Updated FullConfig status", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name, "AppliedNodes", appliedNodes, "TargetNodes", len(nodeStatuses))
+	return nil
+}
+
+// setConditions derives the conditions of the FullConfig from the outcome of the merge of its spec and the per-node delivery status.
+func (r *FullConfigReconciler) setConditions(fullConfig *iprulerv1.FullConfig, merge *specMerge) {
+	conditions := &fullConfig.Status.Conditions
+	generation := fullConfig.Generation
+
+	if merge.err != nil {
+		setCondition(conditions, iprulerv1.ConditionTypeMerged, false, iprulerv1.ReasonInvalidConfig,
+			fmt.Sprintf("spec.mergedConfig is kept as is, spec.clusterConfig and spec.nodeConfig can't be merged: %v", merge.err), generation)
+	} else if reflect.DeepEqual(fullConfig.Spec.MergedConfig, merge.config) {
+		setCondition(conditions, iprulerv1.ConditionTypeMerged, true, iprulerv1.ReasonMerged,
+			"spec.mergedConfig reflects spec.clusterConfig and spec.nodeConfig", generation)
+	} else if revision, ok := fullConfig.Annotations[iprulerv1.RolledBackAnnotation]; ok {
+		setCondition(conditions, iprulerv1.ConditionTypeMerged, true, iprulerv1.ReasonRolledBack,
+			fmt.Sprintf("spec.mergedConfig is rolled back to revision %s until the ClusterConfigs or the NodeConfig change", revision), generation)
+	} else {
+		setCondition(conditions, iprulerv1.ConditionTypeMerged, false, iprulerv1.ReasonMergePending,
+			"spec.mergedConfig is not merged from spec.clusterConfig and spec.nodeConfig yet", generation)
+	}
+
+	var failedNodes, unavailableNodes []string
+	for _, nodeStatus := range fullConfig.Status.Nodes {
+		if nodeStatus.Applied {
+			continue
+		}
+		if nodeStatus.AgentPod == "" {
+			unavailableNodes = append(unavailableNodes, nodeStatus.NodeName)
+		} else {
+			failedNodes = append(failedNodes, nodeStatus.NodeName)
+		}
+	}
+
+	appliedMessage := fmt.Sprintf("The merged config is applied on %d/%d nodes", fullConfig.Status.AppliedNodes, fullConfig.Status.TargetNodes)
+	if fullConfig.Status.AppliedNodes == fullConfig.Status.TargetNodes {
+		setCondition(conditions, iprulerv1.ConditionTypeApplied, true, iprulerv1.ReasonApplied, appliedMessage, generation)
+	} else {
+		setCondition(conditions, iprulerv1.ConditionTypeApplied, false, iprulerv1.ReasonApplyPending, appliedMessage, generation)
+	}
+
+	switch {
+	case len(merge.invalid) > 0:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, true, iprulerv1.ReasonInvalidConfig,
+			fmt.Sprintf("The merged config is not injected into the nodes: %s", merge.invalid.ToAggregate()), generation)
+	case fullConfig.Status.Rollout != nil && fullConfig.Status.Rollout.Phase == iprulerv1.RolloutPhaseRolledBack:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, true, iprulerv1.ReasonRolledBack, fullConfig.Status.Rollout.Message, generation)
+	case len(failedNodes) > 0:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, true, iprulerv1.ReasonApplyFailed,
+			fmt.Sprintf("The agents of nodes %s failed to apply the config", strings.Join(failedNodes, ", ")), generation)
+	case len(unavailableNodes) > 0:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, true, iprulerv1.ReasonAgentUnavailable,
+			fmt.Sprintf("Nodes %s have no ready agent", strings.Join(unavailableNodes, ", ")), generation)
+	default:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, false, iprulerv1.ReasonAsExpected,
+			"Every targeted node has a ready agent that accepted the config", generation)
+	}
+
+	var driftedNodes []string
+	for _, nodeStatus := range fullConfig.Status.Nodes {
+		if nodeStatus.Drifted {
+			driftedNodes = append(driftedNodes, nodeStatus.NodeName)
+		}
+	}
+	if len(driftedNodes) > 0 {
+		setCondition(conditions, iprulerv1.ConditionTypeDrifted, true, iprulerv1.ReasonDriftDetected,
+			fmt.Sprintf("The config applied on nodes %s drifted from the merged config", strings.Join(driftedNodes, ", ")), generation)
+	} else {
+		setCondition(conditions, iprulerv1.ConditionTypeDrifted, false, iprulerv1.ReasonNoDrift,
+			"The config applied on the nodes matches the merged config", generation)
+	}
+
+	setReadyCondition(conditions, generation)
 }
 
 func (r *FullConfigReconciler) handleFinalizer(ctx context.Context, fullConfig *iprulerv1.FullConfig) error {
@@ -126,34 +487,51 @@ func (r *FullConfigReconciler) handleFinalizer(ctx context.Context, fullConfig *
 }
 
 func (r *FullConfigReconciler) handleDeletion(ctx context.Context, fullConfig *iprulerv1.FullConfig) (ctrl.Result, error) {
-	if envirnment.NodeCleanUpOnDeletion {
-		podList := &corev1.PodList{}
-		if err := r.List(ctx, podList, client.MatchingLabels{globalAgentManager.AppLabelKey: globalAgentManager.AppLabelValue}, client.InNamespace(globalAgentManager.Namespace)); err != nil {
-			r.Log.Error(err, "Failed to get pods list")
-			return ctrl.Result{}, err
-		}
-		for _, pod := range podList.Items {
-			if PodIsReady(&pod) {
-				var node corev1.Node
-				if err := r.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, &node); err != nil {
-					r.Log.Error(err, "message", "Failed to get pods's node", "Pod", pod.Name)
-					return ctrl.Result{Requeue: true}, err
-				}
-				labelMatch := true
-				nodeLabels := node.GetLabels()
-				for key, value := range fullConfig.Spec.NodeSelector {
-					r.Log.Info("deleted full config node selector", "key", key, "value", value)
-					if nodeLabels[key] != value {
-						labelMatch = false
-						break
-					}
-				}
-				if labelMatch {
-					globalAgentManager.Cleanup(&pod)
+	fullConfigList := &iprulerv1.FullConfigList{}
+	if err := r.List(ctx, fullConfigList); err != nil {
+		r.Log.Error(err, "Failed to List FullConfig")
+		return ctrl.Result{}, err
+	}
+
+	// the FullConfigs that shared nodes with the deleted one may now own those nodes alone
+	sharedWith := map[string]bool{}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.MatchingLabels{globalAgentManager.AppLabelKey: globalAgentManager.AppLabelValue}, client.InNamespace(globalAgentManager.Namespace)); err != nil {
+		r.Log.Error(err, "Failed to get pods list")
+		return ctrl.Result{}, err
+	}
+	for _, pod := range podList.Items {
+		if PodIsReady(&pod) {
+			var node corev1.Node
+			if err := r.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, &node); err != nil {
+				r.Log.Error(err, "message", "Failed to get pods's node", "Pod", pod.Name)
+				return ctrl.Result{Requeue: true}, err
+			}
+			if !nodeMatchesSelector(&node, fullConfig.Spec.NodeSelector) {
+				continue
+			}
+			if others := overlappingFullConfigs(&node, fullConfig, fullConfigList.Items); len(others) > 0 {
+				for _, other := range others {
+					sharedWith[other] = true
 				}
+				continue
+			}
+			if envirnment.NodeCleanUpOnDeletion {
+				globalAgentManager.Cleanup(&pod)
 			}
 		}
 	}
+
+	for _, other := range fullConfigList.Items {
+		if !sharedWith[other.Name] {
+			continue
+		}
+		if err := triggerFullConfig(ctx, r.Client, &other); err != nil {
+			r.Log.Error(err, "Failed to update FullConfig to trigger reconciliation", "Namespace", other.Namespace, "Name", other.Name)
+			return ctrl.Result{}, err
+		}
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -163,7 +541,10 @@ func (r *FullConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&iprulerv1.FullConfig{}).
 		WithEventFilter(predicate.Funcs{
 			UpdateFunc: func(e event.UpdateEvent) bool {
-				return true
+				// status updates must not trigger another injection
+				return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() ||
+					!reflect.DeepEqual(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations()) ||
+					!e.ObjectNew.GetDeletionTimestamp().IsZero()
 			},
 			CreateFunc: func(e event.CreateEvent) bool {
 				return true
@@ -2,18 +2,27 @@ package controller
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	env "github.com/Netflix/go-env"
 	"github.com/go-logr/logr"
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
 	"github.com/plutocholia/ipruler-operator/internal/models"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Environment struct {
@@ -23,7 +32,22 @@ type Environment struct {
 	IPRulerAgentLabelValue  string `env:"IPRULER_AGENT_LABEL_VALUE,default=ipruler-agent"`
 	IPRulerAgentUpdatePath  string `env:"IPRULER_AGENT_UPDATE_PATH,default=update"`
 	IPRulerAgentCleanupPath string `env:"IPRULER_AGENT_CLEANUP_PATH,default=cleanup"`
+	IPRulerAgentStatePath   string `env:"IPRULER_AGENT_STATE_PATH,default=state"`
 	NodeCleanUpOnDeletion   bool   `env:"NODE_CLEANUP_ON_DELETION,default=true"`
+	ClusterConfigSingleton  bool   `env:"CLUSTER_CONFIG_SINGLETON,default=false"`
+	// SecretsNamespace is the only namespace the Secrets the configs refer to are read from
+	SecretsNamespace string `env:"SECRETS_NAMESPACE,default=kube-system"`
+
+	AgentInjectMaxAttempts int           `env:"AGENT_INJECT_MAX_ATTEMPTS,default=5"`
+	AgentInjectBackoffBase time.Duration `env:"AGENT_INJECT_BACKOFF_BASE,default=5s"`
+	AgentInjectBackoffMax  time.Duration `env:"AGENT_INJECT_BACKOFF_MAX,default=5m"`
+	AgentInjectConcurrency int           `env:"AGENT_INJECT_CONCURRENCY,default=10"`
+	AgentRequestTimeout    time.Duration `env:"AGENT_REQUEST_TIMEOUT,default=10s"`
+
+	ConfigRevisionHistoryLimit int `env:"CONFIG_REVISION_HISTORY_LIMIT,default=10"`
+
+	DriftCheckInterval time.Duration `env:"DRIFT_CHECK_INTERVAL,default=0s"`
+	ResyncInterval     time.Duration `env:"RESYNC_INTERVAL,default=0s"`
 }
 
 func (e *Environment) String() string {
@@ -35,60 +59,226 @@ Environments:
 	IPRulerAgentLabelValue: %s
 	IPRulerAgentUpdatePath: %s
 	IPRulerAgentCleanupPath: %s
+	IPRulerAgentStatePath: %s
 	NodeCleanUpOnDeletion %t
-`, e.IPRulerAgentPort, e.IPRulerAgentNamespace, e.IPRulerAgentLabelKey, e.IPRulerAgentLabelValue, e.IPRulerAgentUpdatePath, e.IPRulerAgentCleanupPath, e.NodeCleanUpOnDeletion)
+	ClusterConfigSingleton: %t
+	SecretsNamespace: %s
+	AgentInjectMaxAttempts: %d
+	AgentInjectBackoffBase: %s
+	AgentInjectBackoffMax: %s
+	AgentInjectConcurrency: %d
+	AgentRequestTimeout: %s
+	ConfigRevisionHistoryLimit: %d
+	DriftCheckInterval: %s
+	ResyncInterval: %s
+`, e.IPRulerAgentPort, e.IPRulerAgentNamespace, e.IPRulerAgentLabelKey, e.IPRulerAgentLabelValue, e.IPRulerAgentUpdatePath, e.IPRulerAgentCleanupPath, e.IPRulerAgentStatePath, e.NodeCleanUpOnDeletion, e.ClusterConfigSingleton, e.SecretsNamespace,
+		e.AgentInjectMaxAttempts, e.AgentInjectBackoffBase, e.AgentInjectBackoffMax, e.AgentInjectConcurrency, e.AgentRequestTimeout,
+		e.ConfigRevisionHistoryLimit, e.DriftCheckInterval, e.ResyncInterval)
+}
+
+// GetEnvironment returns the environment the operator has been started with.
+func GetEnvironment() Environment {
+	return envirnment
 }
 
 type SharedFullConfig struct {
-	Mutex                  sync.Mutex
-	ClusterConfigName      string
-	ClusterConfigNamespace string
+	Mutex sync.Mutex
 }
 
 type AgentManager struct {
 	Port          int
 	UpdatePath    string
 	CleanupPath   string
+	StatePath     string
 	Namespace     string
 	AppLabelKey   string
 	AppLabelValue string
+	MaxAttempts   int
+	BackoffBase   time.Duration
+	BackoffMax    time.Duration
+	Concurrency   int
+	HTTPClient    *http.Client
 	Log           logr.Logger
 }
 
+// InjectRequest is a config to be injected into an agent pod.
+// When HealthCheckPath is set, the agent has to report healthy on it after the injection.
+type InjectRequest struct {
+	Pod             *corev1.Pod
+	Config          *models.ConfigModel
+	HealthCheckPath string
+}
+
+// errStateUnsupported is returned by FetchState when the agent doesn't serve the config applied on the node.
+var errStateUnsupported = errors.New("the agent doesn't support reading back the applied config")
+
 var (
 	envirnment         Environment
 	globalAgentManager *AgentManager
 	sharedFullConfig   *SharedFullConfig
 )
 
-func (mgr *AgentManager) InjectConfig(pod *corev1.Pod, config *models.ConfigModel) {
+// InjectConfigs injects the configs into the agent pods concurrently, with at most Concurrency requests in flight.
+// It returns the result of every request, in the order of the requests.
+func (mgr *AgentManager) InjectConfigs(ctx context.Context, requests []InjectRequest) []error {
+	errs := make([]error, len(requests))
+	mgr.forEach(len(requests), func(i int) {
+		errs[i] = mgr.InjectConfig(ctx, requests[i].Pod, requests[i].Config)
+		if errs[i] == nil && requests[i].HealthCheckPath != "" {
+			errs[i] = mgr.CheckHealth(ctx, requests[i].Pod, requests[i].HealthCheckPath)
+		}
+	})
+	return errs
+}
+
+// FetchStates reads back the configs applied by the agent pods concurrently, with at most Concurrency requests in flight.
+// It returns the configs and the errors in the order of the pods.
+func (mgr *AgentManager) FetchStates(ctx context.Context, pods []*corev1.Pod) ([]*models.ConfigModel, []error) {
+	configs := make([]*models.ConfigModel, len(pods))
+	errs := make([]error, len(pods))
+	mgr.forEach(len(pods), func(i int) {
+		configs[i], errs[i] = mgr.FetchState(ctx, pods[i])
+	})
+	return configs, errs
+}
+
+// forEach calls fn for every index up to n, with at most Concurrency calls running at the same time.
+func (mgr *AgentManager) forEach(n int, fn func(i int)) {
+	concurrency := mgr.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// InjectConfig sends the config to the agent pod and returns an error if the agent didn't accept it.
+func (mgr *AgentManager) InjectConfig(ctx context.Context, pod *corev1.Pod, config *models.ConfigModel) error {
 	mgr.Log.Info("Injecting config file to", "pod", pod.Name)
 	url := fmt.Sprintf("http://%s:%d/%s", pod.Status.PodIP, mgr.Port, mgr.UpdatePath)
 
-	configYaml, _ := ConvertToYAML(config)
+	configYaml, err := ConvertToYAML(config)
+	if err != nil {
+		mgr.Log.Error(err, "Failed to convert config to yaml", "pod", pod.Name)
+		return err
+	}
 
-	resp, err := http.Post(url, "text/plain", bytes.NewReader([]byte(configYaml)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader([]byte(configYaml)))
+	if err != nil {
+		mgr.Log.Error(err, "Failed to create request", "pod", pod.Name)
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := mgr.HTTPClient.Do(req)
 	if err != nil {
 		mgr.Log.Error(err, "Failed to send request", "pod", pod.Name)
-		return
+		return err
 	}
+	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		mgr.Log.Error(err, "Failed to read response", "pod", pod.Name)
-		resp.Body.Close()
-		return
+		return err
 	}
 
 	mgr.Log.Info("Injecting response from pod", "pod", pod.Name, "response", string(body))
-	resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("agent responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// FetchState reads back the config that is currently applied by the agent pod.
+// Agents that don't serve it, i.e. respond with 404 Not Found, result in errStateUnsupported.
+func (mgr *AgentManager) FetchState(ctx context.Context, pod *corev1.Pod) (*models.ConfigModel, error) {
+	url := fmt.Sprintf("http://%s:%d/%s", pod.Status.PodIP, mgr.Port, mgr.StatePath)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		mgr.Log.Error(err, "Failed to create state request", "pod", pod.Name)
+		return nil, err
+	}
+
+	resp, err := mgr.HTTPClient.Do(req)
+	if err != nil {
+		mgr.Log.Error(err, "Failed to send state request", "pod", pod.Name)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		mgr.Log.Error(err, "Failed to read state response", "pod", pod.Name)
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errStateUnsupported
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("agent responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	config := &models.ConfigModel{}
+	if err := yaml.Unmarshal(body, config); err != nil {
+		return nil, fmt.Errorf("failed to parse the state of the agent: %w", err)
+	}
+	return config, nil
+}
+
+// CheckHealth probes the given path of the agent pod and returns an error unless it responds with a 2xx status.
+func (mgr *AgentManager) CheckHealth(ctx context.Context, pod *corev1.Pod, path string) error {
+	url := fmt.Sprintf("http://%s:%d/%s", pod.Status.PodIP, mgr.Port, strings.TrimPrefix(path, "/"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		mgr.Log.Error(err, "Failed to create health check request", "pod", pod.Name)
+		return err
+	}
+
+	resp, err := mgr.HTTPClient.Do(req)
+	if err != nil {
+		mgr.Log.Error(err, "Failed to send health check request", "pod", pod.Name)
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("health check failed, agent responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Backoff returns how long to wait before retrying an injection that has failed the given number of times in a row.
+func (mgr *AgentManager) Backoff(attempts int) time.Duration {
+	backoff := mgr.BackoffBase
+	for i := 1; i < attempts && backoff < mgr.BackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > mgr.BackoffMax {
+		backoff = mgr.BackoffMax
+	}
+	return backoff
 }
 
 func (mgr *AgentManager) Cleanup(pod *corev1.Pod) {
 	mgr.Log.Info("Cleaup", "pod", pod.Name)
 	url := fmt.Sprintf("http://%s:%d/%s", pod.Status.PodIP, mgr.Port, mgr.CleanupPath)
 
-	resp, err := http.Post(url, "text/plain", nil)
+	resp, err := mgr.HTTPClient.Post(url, "text/plain", nil)
 	if err != nil {
 		mgr.Log.Error(err, "Failed to send cleanup request", "pod", pod.Name)
 		return
@@ -122,6 +312,75 @@ func PodIsReady(pod *corev1.Pod) bool {
 	return false
 }
 
+// nodeMatchesSelector reports whether the node has all the labels of the given selector.
+func nodeMatchesSelector(node *corev1.Node, selector map[string]string) bool {
+	nodeLabels := node.GetLabels()
+	for key, value := range selector {
+		if nodeLabels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// overlappingFullConfigs returns the names of the FullConfigs other than the given one that select the node too.
+// FullConfigs that are being deleted are not taken into account.
+func overlappingFullConfigs(node *corev1.Node, fullConfig *iprulerv1.FullConfig, fullConfigs []iprulerv1.FullConfig) []string {
+	var names []string
+	for _, other := range fullConfigs {
+		if other.Name == fullConfig.Name || !other.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if nodeMatchesSelector(node, other.Spec.NodeSelector) {
+			names = append(names, other.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// triggerFullConfig updates the lastUpdateTrigger annotation of the FullConfig, so that the FullConfig gets
+// reconciled and its config gets injected into the agents again.
+func triggerFullConfig(ctx context.Context, c client.Client, fullConfig *iprulerv1.FullConfig) error {
+	if fullConfig.Annotations == nil {
+		fullConfig.Annotations = map[string]string{}
+	}
+	fullConfig.Annotations["lastUpdateTrigger"] = time.Now().Format(time.RFC3339)
+	return c.Update(ctx, fullConfig)
+}
+
+// mergeFullConfigSpec merges the node config of the FullConfig on top of its cluster config, once the entries excluded
+// by the NodeConfig are left out of the latter, resolves the table names the rules and routes refer to and leaves out
+// the sysctls that are not allowed.
+// The entries of the cluster config overridden by the node config and the excluded ones are returned along with the
+// merged config. An invalid exclude block is an error, as leaving out nothing could inject unwanted entries.
+func mergeFullConfigSpec(spec *iprulerv1.FullConfigSpec) (models.ConfigModel, []string, []string, error) {
+	clusterConfig, excluded, err := models.ExcludeEntries(&spec.ClusterConfig, spec.Exclude)
+	if err != nil {
+		return models.ConfigModel{}, nil, nil, err
+	}
+	mergedConfig, overrides := models.MergeConfigModels(&clusterConfig, &spec.NodeConfig)
+	if unknown := models.ResolveTableNames(&mergedConfig); len(unknown) > 0 {
+		// the webhooks reject unknown table names, unless the ClusterConfig defining them was deleted since
+		ctrl.Log.WithName("merge").Info("Leaving out the rules and routes referring to unknown tables", "tables", unknown)
+	}
+	if dropped := models.FilterSysctls(&mergedConfig); len(dropped) > 0 {
+		// the webhook rejects them, but it is disabled by default
+		ctrl.Log.WithName("merge").Info("Leaving out the sysctls that are not allowed", "sysctls", dropped)
+	}
+	return mergedConfig, overrides, excluded, nil
+}
+
+// configHash returns a short hash identifying the content of the config.
+func configHash(config *models.ConfigModel) (string, error) {
+	configYaml, err := ConvertToYAML(config)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(configYaml))
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
 func ConvertToYAML(v interface{}) (string, error) {
 	data, err := yaml.Marshal(v)
 	if err != nil {
@@ -141,9 +400,15 @@ func init() {
 		Port:          envirnment.IPRulerAgentPort,
 		UpdatePath:    envirnment.IPRulerAgentUpdatePath,
 		CleanupPath:   envirnment.IPRulerAgentCleanupPath,
+		StatePath:     envirnment.IPRulerAgentStatePath,
 		AppLabelKey:   envirnment.IPRulerAgentLabelKey,
 		AppLabelValue: envirnment.IPRulerAgentLabelValue,
 		Namespace:     envirnment.IPRulerAgentNamespace,
+		MaxAttempts:   envirnment.AgentInjectMaxAttempts,
+		BackoffBase:   envirnment.AgentInjectBackoffBase,
+		BackoffMax:    envirnment.AgentInjectBackoffMax,
+		Concurrency:   envirnment.AgentInjectConcurrency,
+		HTTPClient:    &http.Client{Timeout: envirnment.AgentRequestTimeout},
 		Log:           ctrl.Log.WithName("AgentManager"),
 	}
 	sharedFullConfig = &SharedFullConfig{}
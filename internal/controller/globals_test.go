@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// newTestClient returns a fake client holding the given objects.
+func newTestClient(t *testing.T, objects ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := iprulerv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+		WithStatusSubresource(&iprulerv1.FullConfig{}, &iprulerv1.NodeConfig{}, &iprulerv1.ClusterConfig{}).Build()
+}
+
+// setTestAgentManager replaces the global agent manager for the duration of the test.
+func setTestAgentManager(t *testing.T, mgr *AgentManager) {
+	t.Helper()
+	previous := globalAgentManager
+	globalAgentManager = mgr
+	t.Cleanup(func() { globalAgentManager = previous })
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		max      time.Duration
+		attempts int
+		want     time.Duration
+	}{
+		{name: "no attempt yet", base: 5 * time.Second, max: time.Minute, attempts: 0, want: 5 * time.Second},
+		{name: "first attempt", base: 5 * time.Second, max: time.Minute, attempts: 1, want: 5 * time.Second},
+		{name: "doubled on every attempt", base: 5 * time.Second, max: time.Minute, attempts: 3, want: 20 * time.Second},
+		{name: "capped at the max", base: 5 * time.Second, max: time.Minute, attempts: 5, want: time.Minute},
+		{name: "stays at the max", base: 5 * time.Second, max: time.Minute, attempts: 1000, want: time.Minute},
+		{name: "base above the max", base: 2 * time.Minute, max: time.Minute, attempts: 1, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := &AgentManager{BackoffBase: tt.base, BackoffMax: tt.max}
+			if got := mgr.Backoff(tt.attempts); got != tt.want {
+				t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEach(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		n           int
+		wantMax     int
+	}{
+		{name: "limited concurrency", concurrency: 3, n: 12, wantMax: 3},
+		{name: "more workers than calls", concurrency: 10, n: 4, wantMax: 4},
+		{name: "zero means one at a time", concurrency: 0, n: 4, wantMax: 1},
+		{name: "negative means one at a time", concurrency: -1, n: 4, wantMax: 1},
+		{name: "no calls", concurrency: 3, n: 0, wantMax: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := &AgentManager{Concurrency: tt.concurrency}
+			var inFlight, maxInFlight atomic.Int32
+			var mu sync.Mutex
+			calls := map[int]int{}
+			mgr.forEach(tt.n, func(i int) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					seen := maxInFlight.Load()
+					if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				calls[i]++
+				mu.Unlock()
+			})
+
+			if got := int(maxInFlight.Load()); got > tt.wantMax {
+				t.Errorf("%d calls were in flight at the same time, want at most %d", got, tt.wantMax)
+			}
+			if len(calls) != tt.n {
+				t.Errorf("%d indexes were called, want %d", len(calls), tt.n)
+			}
+			for i, count := range calls {
+				if i < 0 || i >= tt.n || count != 1 {
+					t.Errorf("index %d was called %d times, want once", i, count)
+				}
+			}
+		})
+	}
+}
+
+// newTestAgent starts an agent that rejects the configs with a VLAN named "reject" and returns a manager talking to it.
+func newTestAgent(t *testing.T, concurrency int) *AgentManager {
+	t.Helper()
+	var inFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inFlight.Add(1) > int32(max(concurrency, 1)) {
+			t.Errorf("more than %d requests are in flight", concurrency)
+		}
+		defer inFlight.Add(-1)
+		time.Sleep(10 * time.Millisecond)
+		switch r.URL.Path {
+		case "/update":
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), "name: reject") {
+				http.Error(w, "invalid config", http.StatusBadRequest)
+			}
+		case "/state":
+			_, _ = io.WriteString(w, "vlans:\n- name: vlan.100\n  link: eth0\n  id: 100\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &AgentManager{UpdatePath: "update", StatePath: "state", Concurrency: concurrency, HTTPClient: server.Client(), Log: logr.Discard()}
+	mgr.Port, _ = strconv.Atoi(port)
+	return mgr
+}
+
+func testAgentPod(name, ip string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: corev1.PodStatus{PodIP: ip}}
+}
+
+func TestInjectConfigs(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "limited concurrency", concurrency: 2},
+		{name: "zero means one at a time", concurrency: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newTestAgent(t, tt.concurrency)
+			accepted := &models.ConfigModel{Vlans: []models.VlanModel{{Name: "vlan.100", Link: "eth0", ID: 100}}}
+			rejected := &models.ConfigModel{Vlans: []models.VlanModel{{Name: "reject", Link: "eth0", ID: 100}}}
+			wantFailed := []bool{false, true, false, false, true, false}
+			var requests []InjectRequest
+			for i, failed := range wantFailed {
+				config := accepted
+				if failed {
+					config = rejected
+				}
+				requests = append(requests, InjectRequest{Pod: testAgentPod("agent-"+strconv.Itoa(i), "127.0.0.1"), Config: config})
+			}
+
+			errs := mgr.InjectConfigs(context.Background(), requests)
+
+			if len(errs) != len(requests) {
+				t.Fatalf("got %d results, want %d", len(errs), len(requests))
+			}
+			for i, err := range errs {
+				if (err != nil) != wantFailed[i] {
+					t.Errorf("request %d error = %v, want failed %t", i, err, wantFailed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchStates(t *testing.T) {
+	mgr := newTestAgent(t, 0)
+	// the pod with an invalid IP fails before any request is sent
+	pods := []*corev1.Pod{testAgentPod("agent-0", "127.0.0.1"), testAgentPod("agent-1", "%"), testAgentPod("agent-2", "127.0.0.1")}
+
+	configs, errs := mgr.FetchStates(context.Background(), pods)
+
+	if len(configs) != len(pods) || len(errs) != len(pods) {
+		t.Fatalf("got %d configs and %d errors, want %d", len(configs), len(errs), len(pods))
+	}
+	for i := range pods {
+		failed := i == 1
+		if (errs[i] != nil) != failed || (configs[i] == nil) != failed {
+			t.Errorf("pod %d state = %v, %v, want failed %t", i, configs[i], errs[i], failed)
+		}
+		if configs[i] != nil && (len(configs[i].Vlans) != 1 || configs[i].Vlans[0].Name != "vlan.100") {
+			t.Errorf("pod %d state = %+v, want the config applied by the agent", i, configs[i])
+		}
+	}
+}
+
+func TestOverlappingFullConfigs(t *testing.T) {
+	fullConfig := func(name string, selector map[string]string, deleted bool) iprulerv1.FullConfig {
+		fullConfig := iprulerv1.FullConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       iprulerv1.FullConfigSpec{NodeSelector: selector},
+		}
+		if deleted {
+			fullConfig.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		}
+		return fullConfig
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"role": "edge", "zone": "a"}}}
+	edge := fullConfig("edge", map[string]string{"role": "edge"}, false)
+
+	tests := []struct {
+		name        string
+		fullConfigs []iprulerv1.FullConfig
+		want        []string
+	}{
+		{name: "only the FullConfig itself", fullConfigs: []iprulerv1.FullConfig{edge}},
+		{
+			name: "other FullConfigs selecting the node",
+			fullConfigs: []iprulerv1.FullConfig{edge, fullConfig("zone-a", map[string]string{"zone": "a"}, false),
+				fullConfig("all", nil, false), fullConfig("zone-b", map[string]string{"zone": "b"}, false)},
+			want: []string{"all", "zone-a"},
+		},
+		{
+			name:        "FullConfigs selecting other nodes",
+			fullConfigs: []iprulerv1.FullConfig{edge, fullConfig("core", map[string]string{"role": "core"}, false)},
+		},
+		{
+			name:        "FullConfigs being deleted",
+			fullConfigs: []iprulerv1.FullConfig{edge, fullConfig("zone-a", map[string]string{"zone": "a"}, true)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlappingFullConfigs(node, &edge, tt.fullConfigs); !slices.Equal(got, tt.want) {
+				t.Errorf("overlappingFullConfigs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+func TestInjectionRestarted(t *testing.T) {
+	lastAttempt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ipruler-agent-abcde"}}
+	tests := []struct {
+		name        string
+		nodeStatus  iprulerv1.NodeStatus
+		specChanged bool
+		triggeredAt time.Time
+		want        bool
+	}{
+		{name: "never attempted", nodeStatus: iprulerv1.NodeStatus{AgentPod: pod.Name}, want: true},
+		{name: "nothing changed", nodeStatus: iprulerv1.NodeStatus{AgentPod: pod.Name, LastAttemptTime: &metav1.Time{Time: lastAttempt}},
+			triggeredAt: lastAttempt.Add(-time.Minute), want: false},
+		{name: "spec changed", nodeStatus: iprulerv1.NodeStatus{AgentPod: pod.Name, LastAttemptTime: &metav1.Time{Time: lastAttempt}},
+			specChanged: true, want: true},
+		{name: "triggered since the last attempt", nodeStatus: iprulerv1.NodeStatus{AgentPod: pod.Name, LastAttemptTime: &metav1.Time{Time: lastAttempt}},
+			triggeredAt: lastAttempt.Add(time.Second), want: true},
+		{name: "new agent pod", nodeStatus: iprulerv1.NodeStatus{AgentPod: "ipruler-agent-fghij", LastAttemptTime: &metav1.Time{Time: lastAttempt}},
+			want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := injectionRestarted(&tt.nodeStatus, pod, tt.specChanged, tt.triggeredAt); got != tt.want {
+				t.Errorf("injectionRestarted() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInjectionDue(t *testing.T) {
+	setTestAgentManager(t, &AgentManager{MaxAttempts: 3, BackoffBase: 10 * time.Second, BackoffMax: 15 * time.Second})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		nodeStatus iprulerv1.NodeStatus
+		wantDue    bool
+		wantAfter  time.Duration
+	}{
+		{name: "config applied", nodeStatus: iprulerv1.NodeStatus{Applied: true, ConfigHash: "new"}},
+		{name: "older config applied", nodeStatus: iprulerv1.NodeStatus{Applied: true, ConfigHash: "old"}, wantDue: true},
+		{name: "backoff is over", nodeStatus: iprulerv1.NodeStatus{Attempts: 1, LastAttemptTime: &metav1.Time{Time: now.Add(-10 * time.Second)}},
+			wantDue: true},
+		{name: "waiting for the backoff", nodeStatus: iprulerv1.NodeStatus{Attempts: 1, LastAttemptTime: &metav1.Time{Time: now.Add(-4 * time.Second)}},
+			wantAfter: 6 * time.Second},
+		{name: "waiting for the capped backoff", nodeStatus: iprulerv1.NodeStatus{Attempts: 2, LastAttemptTime: &metav1.Time{Time: now.Add(-5 * time.Second)}},
+			wantAfter: 10 * time.Second},
+		{name: "given up after the max attempts", nodeStatus: iprulerv1.NodeStatus{Attempts: 3, LastAttemptTime: &metav1.Time{Time: now.Add(-time.Hour)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, after := injectionDue(&tt.nodeStatus, "new", now)
+			if due != tt.wantDue || after != tt.wantAfter {
+				t.Errorf("injectionDue() = %t, %v, want %t, %v", due, after, tt.wantDue, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestRecordInjectionFailure(t *testing.T) {
+	setTestAgentManager(t, &AgentManager{MaxAttempts: 3, BackoffBase: 10 * time.Second, BackoffMax: 15 * time.Second})
+	nodeStatus := &iprulerv1.NodeStatus{Applied: true, ConfigHash: "old"}
+	for attempt, want := range []time.Duration{10 * time.Second, 15 * time.Second, 0} {
+		if got := recordInjectionFailure(nodeStatus, errors.NewBadRequest("rejected")); got != want {
+			t.Errorf("requeue after attempt %d = %v, want %v", attempt+1, got, want)
+		}
+		if nodeStatus.Applied || nodeStatus.Attempts != attempt+1 {
+			t.Errorf("node status after attempt %d = applied %t, attempts %d", attempt+1, nodeStatus.Applied, nodeStatus.Attempts)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package controller
 import (
 	"context"
 	"reflect"
-	"time"
+	"strings"
 
 	"github.com/go-logr/logr"
 	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
@@ -18,6 +18,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// templateAnnotationPrefix is the prefix of the node annotations the templates of the configs may refer to.
+// Other annotations change too often, e.g. by the kubelet and other controllers, to inject the configs again on every change.
+const templateAnnotationPrefix = "ipruler.pegah.tech/"
+
 type NodeReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
@@ -69,38 +73,21 @@ func (r *NodeReconciler) handleUpdateOrCreate(ctx context.Context, node *corev1.
 		return ctrl.Result{}, err
 	}
 
-	// find the FullConfig corresponding to the node
-	var matchedFullConfig *iprulerv1.FullConfig
+	// trigger every FullConfig corresponding to the node for doing request stuff, the FullConfigs themselves
+	// refuse to inject into the node if it is selected by more than one of them
 	for _, fullConfig := range fullConfigList.Items {
-		fullConfigMatchTheNode := true
-		for fullConfigLabelKey, fullConfigLabelValue := range fullConfig.Spec.NodeSelector {
-			if node.GetLabels()[fullConfigLabelKey] != fullConfigLabelValue {
-				fullConfigMatchTheNode = false
-				break
-			}
+		if !nodeMatchesSelector(node, fullConfig.Spec.NodeSelector) {
+			continue
 		}
-		if fullConfigMatchTheNode {
-			matchedFullConfig = &fullConfig
-		}
-	}
-
-	// trigger the FullConfig resource for doing request stuff
-	if matchedFullConfig != nil {
-		if matchedFullConfig.Annotations == nil {
-			matchedFullConfig.Annotations = map[string]string{}
-		}
-		matchedFullConfig.Annotations["lastUpdateTrigger"] = time.Now().Format(time.RFC3339)
-		if err := r.Client.Update(ctx, matchedFullConfig); err != nil && apierrors.IsConflict(err) {
-			r.Log.Info("Conflict in resource when updating lastUpdateTrigger annotation. The given FullConfig has been changed", "Namespace", matchedFullConfig.Namespace, "Name", matchedFullConfig.Name)
+		if err := triggerFullConfig(ctx, r.Client, &fullConfig); err != nil && apierrors.IsConflict(err) {
+			r.Log.Info("Conflict in resource when updating lastUpdateTrigger annotation. The given FullConfig has been changed", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
 			return ctrl.Result{}, err
 		} else if err != nil {
-			r.Log.Error(err, "Failed to update FullConfig on lastUpdateTrigger annotation", "Namespace", matchedFullConfig.Namespace, "Name", matchedFullConfig.Name)
+			r.Log.Error(err, "Failed to update FullConfig on lastUpdateTrigger annotation", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
 			return ctrl.Result{}, err
 		} else {
-			r.Log.Info("Updated FullConfig on lastUpdateTrigger", "Namespace", matchedFullConfig.Namespace, "Name", matchedFullConfig.Name)
+			r.Log.Info("Updated FullConfig on lastUpdateTrigger", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
 		}
-
-		return ctrl.Result{}, nil
 	}
 
 	return ctrl.Result{}, nil
@@ -119,10 +106,7 @@ func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				return true
 			},
 			UpdateFunc: func(e event.UpdateEvent) bool {
-				oldNode := e.ObjectOld.(*corev1.Node)
-				newNode := e.ObjectNew.(*corev1.Node)
-				// if there is changes in the node labels or node status
-				return !reflect.DeepEqual(oldNode.GetLabels(), newNode.GetLabels()) || !reflect.DeepEqual(oldNode.Status.Conditions, newNode.Status.Conditions)
+				return nodeChanged(e.ObjectOld.(*corev1.Node), e.ObjectNew.(*corev1.Node))
 			},
 			DeleteFunc: func(e event.DeleteEvent) bool {
 				return false
@@ -133,3 +117,22 @@ func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}).
 		Complete(r)
 }
+
+// nodeChanged reports whether the labels, the annotations the configs may be rendered from or the status of the node
+// have changed, so that the configs have to be injected into it again.
+func nodeChanged(oldNode, newNode *corev1.Node) bool {
+	return !reflect.DeepEqual(oldNode.GetLabels(), newNode.GetLabels()) ||
+		!reflect.DeepEqual(templateAnnotations(oldNode), templateAnnotations(newNode)) ||
+		!reflect.DeepEqual(oldNode.Status.Conditions, newNode.Status.Conditions)
+}
+
+// templateAnnotations returns the annotations of the node the templates of the configs may refer to.
+func templateAnnotations(node *corev1.Node) map[string]string {
+	annotations := map[string]string{}
+	for key, value := range node.GetAnnotations() {
+		if strings.HasPrefix(key, templateAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
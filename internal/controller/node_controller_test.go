@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+func TestNodeChanged(t *testing.T) {
+	testNode := func(labels, annotations map[string]string, ready corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: labels, Annotations: annotations},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+	labels := map[string]string{"zone": "a"}
+	annotations := map[string]string{"ipruler.pegah.tech/eth2.104": "172.31.104.11", "node.alpha.kubernetes.io/ttl": "0"}
+	oldNode := testNode(labels, annotations, corev1.ConditionTrue)
+
+	tests := []struct {
+		name    string
+		newNode *corev1.Node
+		want    bool
+	}{
+		{name: "nothing changed", newNode: testNode(labels, annotations, corev1.ConditionTrue)},
+		{name: "labels changed", newNode: testNode(map[string]string{"zone": "b"}, annotations, corev1.ConditionTrue), want: true},
+		{name: "status changed", newNode: testNode(labels, annotations, corev1.ConditionFalse), want: true},
+		{
+			name: "prefixed annotation changed",
+			newNode: testNode(labels, map[string]string{"ipruler.pegah.tech/eth2.104": "172.31.104.12", "node.alpha.kubernetes.io/ttl": "0"},
+				corev1.ConditionTrue),
+			want: true,
+		},
+		{
+			name:    "prefixed annotation removed",
+			newNode: testNode(labels, map[string]string{"node.alpha.kubernetes.io/ttl": "0"}, corev1.ConditionTrue),
+			want:    true,
+		},
+		{
+			name: "other annotation changed",
+			newNode: testNode(labels, map[string]string{"ipruler.pegah.tech/eth2.104": "172.31.104.11", "node.alpha.kubernetes.io/ttl": "30",
+				"volumes.kubernetes.io/controller-managed-attach-detach": "true"}, corev1.ConditionTrue),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeChanged(oldNode, tt.newNode); got != tt.want {
+				t.Errorf("nodeChanged() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderConfigAnnotations(t *testing.T) {
+	target := agentTarget{node: corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "node-a",
+		Annotations: map[string]string{"ipruler.pegah.tech/eth2.104": "172.31.104.11", "example.com/eth2.104": "10.0.0.1"},
+	}}}
+	render := func(annotation string) (*models.ConfigModel, error) {
+		return target.renderConfig(&models.ConfigModel{Addresses: []models.AddressModel{
+			{Dev: "eth2.104", Address: `{{ index .Annotations "` + annotation + `" }}/24`},
+		}})
+	}
+
+	config, err := render("ipruler.pegah.tech/eth2.104")
+	if err != nil || config.Addresses[0].Address != "172.31.104.11/24" {
+		t.Errorf("renderConfig() = %v, %v, want the address of the prefixed annotation", config, err)
+	}
+	if config, err := render("example.com/eth2.104"); err == nil {
+		t.Errorf("renderConfig() = %v, want an error for an annotation without the prefix", config)
+	}
+}
@@ -18,20 +18,27 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
 	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
-	"github.com/plutocholia/ipruler-operator/internal/models"
 )
 
 // NodeConfigReconciler reconciles a NodeConfig object
@@ -71,6 +78,10 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return res, err
 	}
 
+	if err := r.updateStatus(ctx, &nodeConfig); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -89,12 +100,17 @@ func (r *NodeConfigReconciler) handleUpdateOrCreate(ctx context.Context, nodeCon
 		// Create a new FullConfig
 		newFullConfig := &iprulerv1.FullConfig{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      nodeConfig.Name,
-				Namespace: nodeConfig.Namespace,
+				Name:        nodeConfig.Name,
+				Namespace:   nodeConfig.Namespace,
+				Annotations: map[string]string{iprulerv1.AuthorAnnotation: changeAuthor("NodeConfig", nodeConfig)},
 			},
 			Spec: iprulerv1.FullConfigSpec{
-				NodeSelector: nodeConfig.Spec.NodeSelector,
-				NodeConfig:   nodeConfig.Spec.Config,
+				NodeSelector:        nodeConfig.Spec.NodeSelector,
+				NodeConfig:          nodeConfig.Spec.Config,
+				Exclude:             nodeConfig.Spec.Exclude,
+				NodeRolloutStrategy: nodeConfig.Spec.RolloutStrategy,
+				DriftPolicy:         nodeConfig.Spec.DriftPolicy,
+				ResyncInterval:      nodeConfig.Spec.ResyncInterval,
 			},
 		}
 
@@ -120,11 +136,24 @@ func (r *NodeConfigReconciler) handleUpdateOrCreate(ctx context.Context, nodeCon
 	}
 
 	// Check if the FullConfig needs to be updated
-	if !reflect.DeepEqual(fullConfig.Spec.NodeConfig, nodeConfig.Spec.Config) {
+	if fullConfigOutdated(fullConfig, nodeConfig) {
 		// update spec
 		fullConfig.Spec.NodeSelector = nodeConfig.Spec.NodeSelector
 		fullConfig.Spec.NodeConfig = nodeConfig.Spec.Config
-		fullConfig.Spec.MergedConfig = models.MergeConfigModels(&nodeConfig.Spec.Config, &fullConfig.Spec.ClusterConfig)
+		fullConfig.Spec.Exclude = nodeConfig.Spec.Exclude
+		fullConfig.Spec.NodeRolloutStrategy = nodeConfig.Spec.RolloutStrategy
+		fullConfig.Spec.DriftPolicy = nodeConfig.Spec.DriftPolicy
+		fullConfig.Spec.ResyncInterval = nodeConfig.Spec.ResyncInterval
+		if mergedConfig, overrides, _, err := mergeFullConfigSpec(&fullConfig.Spec); err != nil {
+			// the merged config is kept until the NodeConfig is fixed, the FullConfig reports the error
+			r.Log.Error(err, "Failed to merge spec.clusterConfig and spec.nodeConfig", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
+		} else {
+			fullConfig.Spec.MergedConfig = mergedConfig
+			if len(overrides) > 0 {
+				r.Log.Info("NodeConfig overrides entries of the ClusterConfigs", "NodeConfig", nodeConfig.Name, "Overrides", overrides)
+			}
+		}
+		setChangeAuthor(fullConfig, changeAuthor("NodeConfig", nodeConfig))
 
 		if err := r.Client.Update(ctx, fullConfig); err != nil && apierrors.IsConflict(err) {
 			r.Log.Info("Conflict in resource when updating spec.nodeSelector, spec.nodeConfig and spec.mergeConfig, the given FullConfig has been changed", "Namespace", fullConfig.Namespace, "Name", fullConfig.Name)
@@ -157,10 +186,162 @@ func (r *NodeConfigReconciler) handleUpdateOrCreate(ctx context.Context, nodeCon
 	return ctrl.Result{}, nil
 }
 
+// fullConfigOutdated reports whether the spec of the FullConfig doesn't reflect the spec of the NodeConfig.
+func fullConfigOutdated(fullConfig *iprulerv1.FullConfig, nodeConfig *iprulerv1.NodeConfig) bool {
+	return !reflect.DeepEqual(fullConfig.Spec.NodeConfig, nodeConfig.Spec.Config) ||
+		!reflect.DeepEqual(fullConfig.Spec.Exclude, nodeConfig.Spec.Exclude) ||
+		!reflect.DeepEqual(fullConfig.Spec.NodeRolloutStrategy, nodeConfig.Spec.RolloutStrategy) ||
+		fullConfig.Spec.DriftPolicy != nodeConfig.Spec.DriftPolicy ||
+		!reflect.DeepEqual(fullConfig.Spec.ResyncInterval, nodeConfig.Spec.ResyncInterval)
+}
+
+// updateStatus records in the status of the NodeConfig the nodes it shares with other NodeConfigs
+// and mirrors the state of its FullConfig in the conditions.
+func (r *NodeConfigReconciler) updateStatus(ctx context.Context, nodeConfig *iprulerv1.NodeConfig) error {
+	conflictingNodes, conflictingNodeConfigs, err := r.findConflicts(ctx, nodeConfig)
+	if err != nil {
+		return err
+	}
+
+	fullConfig := &iprulerv1.FullConfig{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: nodeConfig.Name, Namespace: nodeConfig.Namespace}, fullConfig); err != nil {
+		if !apierrors.IsNotFound(err) {
+			r.Log.Error(err, "Failed to get FullConfig")
+			return err
+		}
+		fullConfig = nil
+	}
+
+	oldStatus := nodeConfig.Status.DeepCopy()
+	conditions := &nodeConfig.Status.Conditions
+	generation := nodeConfig.Generation
+
+	if len(conflictingNodes) > 0 {
+		setCondition(conditions, iprulerv1.ConditionTypeConflict, true, iprulerv1.ReasonOverlappingNodeSelector,
+			fmt.Sprintf("Nodes %s are selected by NodeConfigs %s as well and don't get any config",
+				strings.Join(conflictingNodes, ", "), strings.Join(conflictingNodeConfigs, ", ")), generation)
+	} else {
+		setCondition(conditions, iprulerv1.ConditionTypeConflict, false, iprulerv1.ReasonNoOverlap,
+			"No node is selected by another NodeConfig", generation)
+	}
+
+	switch {
+	case fullConfig == nil:
+		setCondition(conditions, iprulerv1.ConditionTypeMerged, false, iprulerv1.ReasonFullConfigMissing, "The FullConfig is not created yet", generation)
+	case !reflect.DeepEqual(fullConfig.Spec.NodeConfig, nodeConfig.Spec.Config):
+		setCondition(conditions, iprulerv1.ConditionTypeMerged, false, iprulerv1.ReasonMergePending, "The FullConfig doesn't have the current config yet", generation)
+	default:
+		mirrorCondition(conditions, fullConfig.Status.Conditions, iprulerv1.ConditionTypeMerged, iprulerv1.ReasonMergePending, generation)
+	}
+
+	if fullConfig == nil || fullConfig.Status.ObservedGeneration != fullConfig.Generation {
+		setCondition(conditions, iprulerv1.ConditionTypeApplied, false, iprulerv1.ReasonApplyPending, "The merged config is not injected into the agents yet", generation)
+	} else {
+		mirrorCondition(conditions, fullConfig.Status.Conditions, iprulerv1.ConditionTypeApplied, iprulerv1.ReasonApplyPending, generation)
+	}
+
+	switch {
+	case len(conflictingNodes) > 0:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, true, iprulerv1.ReasonOverlappingNodeSelector,
+			fmt.Sprintf("Nodes %s don't get any config because of the conflict", strings.Join(conflictingNodes, ", ")), generation)
+	case fullConfig != nil:
+		mirrorCondition(conditions, fullConfig.Status.Conditions, iprulerv1.ConditionTypeDegraded, iprulerv1.ReasonAsExpected, generation)
+	default:
+		setCondition(conditions, iprulerv1.ConditionTypeDegraded, false, iprulerv1.ReasonAsExpected, "Nothing is degraded", generation)
+	}
+
+	setReadyCondition(conditions, generation)
+	nodeConfig.Status.ConflictingNodes = conflictingNodes
+	nodeConfig.Status.ObservedGeneration = generation
+
+	if reflect.DeepEqual(oldStatus, &nodeConfig.Status) {
+		return nil
+	}
+	if err := r.Client.Status().Update(ctx, nodeConfig); err != nil && apierrors.IsConflict(err) {
+		r.Log.Info("Conflict in resource, the given NodeConfig had been changed", "Name", nodeConfig.Name)
+		return err
+	} else if err != nil {
+		r.Log.Error(err, "Failed to update NodeConfig status", "Name", nodeConfig.Name)
+		return err
+	}
+	r.Log.Info("Updated NodeConfig status", "Name", nodeConfig.Name)
+	return nil
+}
+
+// findConflicts returns the nodes selected by this NodeConfig and by other NodeConfigs as well, along with those NodeConfigs.
+func (r *NodeConfigReconciler) findConflicts(ctx context.Context, nodeConfig *iprulerv1.NodeConfig) ([]string, []string, error) {
+	nodeList := &corev1.NodeList{}
+	if err := r.Client.List(ctx, nodeList); err != nil {
+		r.Log.Error(err, "Failed to List Node")
+		return nil, nil, err
+	}
+	nodeConfigList := &iprulerv1.NodeConfigList{}
+	if err := r.Client.List(ctx, nodeConfigList); err != nil {
+		r.Log.Error(err, "Failed to List NodeConfig")
+		return nil, nil, err
+	}
+
+	var conflictingNodes []string
+	conflictingNodeConfigs := map[string]bool{}
+	for _, node := range nodeList.Items {
+		if !nodeMatchesSelector(&node, nodeConfig.Spec.NodeSelector) {
+			continue
+		}
+		shared := false
+		for _, other := range nodeConfigList.Items {
+			if other.Name == nodeConfig.Name || !other.ObjectMeta.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if nodeMatchesSelector(&node, other.Spec.NodeSelector) {
+				conflictingNodeConfigs[other.Name] = true
+				shared = true
+			}
+		}
+		if shared {
+			conflictingNodes = append(conflictingNodes, node.Name)
+		}
+	}
+	sort.Strings(conflictingNodes)
+
+	others := make([]string, 0, len(conflictingNodeConfigs))
+	for name := range conflictingNodeConfigs {
+		others = append(others, name)
+	}
+	sort.Strings(others)
+
+	return conflictingNodes, others, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&iprulerv1.NodeConfig{}).
 		Owns(&iprulerv1.FullConfig{}).
+		// a NodeConfig may start or stop conflicting with others when any NodeConfig or node label changes
+		Watches(
+			&iprulerv1.NodeConfig{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNodeConfigs),
+		).
+		Watches(
+			&corev1.Node{},
+			handler.EnqueueRequestsFromMapFunc(r.findAllNodeConfigs),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
 		Complete(r)
 }
+
+func (r *NodeConfigReconciler) findAllNodeConfigs(ctx context.Context, obj client.Object) []ctrl.Request {
+	nodeConfigList := &iprulerv1.NodeConfigList{}
+	if err := r.Client.List(ctx, nodeConfigList); err != nil {
+		r.Log.Error(err, "Failed to List NodeConfig")
+		return nil
+	}
+
+	requests := make([]ctrl.Request, 0, len(nodeConfigList.Items))
+	for _, nodeConfig := range nodeConfigList.Items {
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{Name: nodeConfig.Name, Namespace: nodeConfig.Namespace},
+		})
+	}
+	return requests
+}
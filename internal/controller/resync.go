@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+// resyncJitterFactor spreads the resyncs of the FullConfigs, so that they don't all fire at once.
+const resyncJitterFactor = 0.1
+
+// resyncInterval returns how often the merged config of the FullConfig is injected into its nodes again, or zero if never.
+func resyncInterval(fullConfig *iprulerv1.FullConfig) time.Duration {
+	if fullConfig.Spec.ResyncInterval != nil {
+		return fullConfig.Spec.ResyncInterval.Duration
+	}
+	return envirnment.ResyncInterval
+}
+
+// resyncNodes returns the nodes that have the merged config with the given hash applied and are due for a resync,
+// i.e. it was last injected into them at least a resync interval ago. Nodes that are already candidates are skipped.
+// It also returns how long to wait, with some jitter, before the next node is due, or zero if there is nothing to resync.
+func resyncNodes(fullConfig *iprulerv1.FullConfig, targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus, candidates []int, hash string, now time.Time) ([]int, time.Duration) {
+	interval := resyncInterval(fullConfig)
+	if interval <= 0 {
+		return nil, 0
+	}
+
+	var due []int
+	var nextDue time.Duration
+	for i := range nodeStatuses {
+		nodeStatus := &nodeStatuses[i]
+		if targets[i].pod == nil || !nodeStatus.Applied || nodeStatus.ConfigHash != hash || nodeStatus.LastAttemptTime == nil ||
+			slices.Contains(candidates, i) {
+			continue
+		}
+		if resyncAt := nodeStatus.LastAttemptTime.Add(interval); now.Before(resyncAt) {
+			nextDue = minRequeueAfter(nextDue, resyncAt.Sub(now))
+			continue
+		}
+		due = append(due, i)
+	}
+	if len(due) > 0 {
+		// the nodes resynced now are due again after a whole interval
+		nextDue = minRequeueAfter(nextDue, interval)
+	}
+	if nextDue == 0 {
+		return due, 0
+	}
+	return due, wait.Jitter(nextDue, resyncJitterFactor)
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+func TestResyncNodes(t *testing.T) {
+	const hash = "new"
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(-d)} }
+
+	tests := []struct {
+		name            string
+		defaultInterval time.Duration
+		interval        *metav1.Duration
+		setup           func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus)
+		candidates      []int
+		wantDue         []int
+		wantAfter       time.Duration
+	}{
+		{
+			name:  "disabled by default",
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {},
+		},
+		{
+			name:     "disabled by a zero interval",
+			interval: &metav1.Duration{},
+			setup:    func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {},
+		},
+		{
+			name:     "due and not due nodes",
+			interval: &metav1.Duration{Duration: time.Hour},
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[1].LastAttemptTime = ago(20 * time.Minute)
+			},
+			wantDue: []int{0, 2},
+			// node-b is due before the nodes resynced now
+			wantAfter: 40 * time.Minute,
+		},
+		{
+			name:     "none due",
+			interval: &metav1.Duration{Duration: time.Hour},
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].LastAttemptTime = ago(10 * time.Minute)
+				nodeStatuses[1].LastAttemptTime = ago(50 * time.Minute)
+				nodeStatuses[2].LastAttemptTime = ago(30 * time.Minute)
+			},
+			wantAfter: 10 * time.Minute,
+		},
+		{
+			name:      "resynced nodes are due again after an interval",
+			interval:  &metav1.Duration{Duration: time.Hour},
+			setup:     func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {},
+			wantDue:   []int{0, 1, 2},
+			wantAfter: time.Hour,
+		},
+		{
+			name:            "default interval",
+			defaultInterval: time.Hour,
+			setup:           func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {},
+			wantDue:         []int{0, 1, 2},
+			wantAfter:       time.Hour,
+		},
+		{
+			name:            "interval of the FullConfig takes precedence",
+			defaultInterval: time.Hour,
+			interval:        &metav1.Duration{},
+			setup:           func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {},
+		},
+		{
+			name:     "candidates and nodes without the config are skipped",
+			interval: &metav1.Duration{Duration: time.Hour},
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				targets[1].pod = nil
+				nodeStatuses[2].ConfigHash = "old"
+			},
+			candidates: []int{0},
+		},
+		{
+			name:     "failed nodes are skipped",
+			interval: &metav1.Duration{Duration: time.Hour},
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].Applied = false
+				nodeStatuses[1].LastAttemptTime = nil
+			},
+			wantDue:   []int{2},
+			wantAfter: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultInterval := envirnment.ResyncInterval
+			envirnment.ResyncInterval = tt.defaultInterval
+			defer func() { envirnment.ResyncInterval = defaultInterval }()
+
+			targets := testTargets("node-a", "node-b", "node-c")
+			nodeStatuses := testNodeStatuses(targets, hash)
+			for i := range nodeStatuses {
+				nodeStatuses[i].LastAttemptTime = ago(2 * time.Hour)
+			}
+			tt.setup(targets, nodeStatuses)
+			fullConfig := &iprulerv1.FullConfig{Spec: iprulerv1.FullConfigSpec{ResyncInterval: tt.interval}}
+
+			due, after := resyncNodes(fullConfig, targets, nodeStatuses, tt.candidates, hash, now)
+
+			if !slices.Equal(due, tt.wantDue) {
+				t.Errorf("due nodes = %v, want %v", due, tt.wantDue)
+			}
+			maxAfter := tt.wantAfter + time.Duration(float64(tt.wantAfter)*resyncJitterFactor)
+			if after < tt.wantAfter || after > maxAfter {
+				t.Errorf("requeue after %v, want between %v and %v", after, tt.wantAfter, maxAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+// listRevisions returns the ConfigRevisions of the FullConfig in ascending order of revision.
+func (r *FullConfigReconciler) listRevisions(ctx context.Context, fullConfig *iprulerv1.FullConfig) ([]iprulerv1.ConfigRevision, error) {
+	revisionList := &iprulerv1.ConfigRevisionList{}
+	if err := r.List(ctx, revisionList, client.MatchingLabels{iprulerv1.FullConfigLabel: fullConfig.Name}); err != nil {
+		r.Log.Error(err, "Failed to List ConfigRevision", "FullConfig", fullConfig.Name)
+		return nil, err
+	}
+	revisions := revisionList.Items
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Spec.Revision < revisions[j].Spec.Revision
+	})
+	return revisions, nil
+}
+
+// recordRevision keeps spec.mergedConfig of the FullConfig, which has the given hash, as a new ConfigRevision
+// unless the latest revision already holds it, and deletes the revisions beyond the history limit.
+// The revision number is recorded in the status of the FullConfig.
+func (r *FullConfigReconciler) recordRevision(ctx context.Context, fullConfig *iprulerv1.FullConfig, hash string) error {
+	revisions, err := r.listRevisions(ctx, fullConfig)
+	if err != nil {
+		return err
+	}
+
+	var latest int64
+	if len(revisions) > 0 {
+		latest = revisions[len(revisions)-1].Spec.Revision
+		if revisions[len(revisions)-1].Spec.ConfigHash == hash {
+			fullConfig.Status.CurrentRevision = latest
+			return nil
+		}
+	}
+
+	revision := &iprulerv1.ConfigRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("%s-%d", fullConfig.Name, latest+1),
+			Labels: map[string]string{iprulerv1.FullConfigLabel: fullConfig.Name},
+		},
+		Spec: iprulerv1.ConfigRevisionSpec{
+			FullConfig: fullConfig.Name,
+			Revision:   latest + 1,
+			ConfigHash: hash,
+			Author:     fullConfig.Annotations[iprulerv1.AuthorAnnotation],
+			Config:     *fullConfig.Spec.MergedConfig.DeepCopy(),
+		},
+	}
+	if err := controllerutil.SetControllerReference(fullConfig, revision, r.Scheme); err != nil {
+		r.Log.Error(err, "Failed to set owner reference on new ConfigRevision")
+		return err
+	}
+	if err := r.Create(ctx, revision); err != nil {
+		r.Log.Error(err, "Failed to create new ConfigRevision", "Name", revision.Name)
+		return err
+	}
+	r.Log.Info("Created a new ConfigRevision", "Name", revision.Name, "FullConfig", fullConfig.Name, "ConfigHash", hash)
+	fullConfig.Status.CurrentRevision = revision.Spec.Revision
+
+	revisions = append(revisions, *revision)
+	for len(revisions) > max(envirnment.ConfigRevisionHistoryLimit, 1) {
+		if err := r.Delete(ctx, &revisions[0]); err != nil && !apierrors.IsNotFound(err) {
+			r.Log.Error(err, "Failed to delete old ConfigRevision", "Name", revisions[0].Name)
+			return err
+		}
+		revisions = revisions[1:]
+	}
+	return nil
+}
+
+// handleRollback rolls spec.mergedConfig of the FullConfig back to the revision requested by the rollback annotation.
+// The merged config stays rolled back until the ClusterConfigs or the NodeConfig change it again.
+func (r *FullConfigReconciler) handleRollback(ctx context.Context, fullConfig *iprulerv1.FullConfig) error {
+	requested := fullConfig.Annotations[iprulerv1.RollbackAnnotation]
+	delete(fullConfig.Annotations, iprulerv1.RollbackAnnotation)
+
+	revisions, err := r.listRevisions(ctx, fullConfig)
+	if err != nil {
+		return err
+	}
+
+	var target *iprulerv1.ConfigRevision
+	if number, err := strconv.ParseInt(requested, 10, 64); err == nil {
+		for i := range revisions {
+			if revisions[i].Spec.Revision == number {
+				target = &revisions[i]
+			}
+		}
+	}
+
+	if target == nil {
+		r.Log.Info("Ignoring the rollback to an unknown revision", "Name", fullConfig.Name, "Revision", requested)
+	} else {
+		fullConfig.Spec.MergedConfig = *target.Spec.Config.DeepCopy()
+		fullConfig.Annotations[iprulerv1.RolledBackAnnotation] = strconv.FormatInt(target.Spec.Revision, 10)
+		fullConfig.Annotations[iprulerv1.AuthorAnnotation] = fmt.Sprintf("rollback to revision %d", target.Spec.Revision)
+	}
+
+	if err := r.Update(ctx, fullConfig); err != nil {
+		r.Log.Error(err, "Failed to roll back FullConfig", "Name", fullConfig.Name, "Revision", requested)
+		return err
+	}
+	if target != nil {
+		r.Log.Info("Rolled back FullConfig", "Name", fullConfig.Name, "Revision", target.Spec.Revision)
+	}
+	return nil
+}
+
+// changeAuthor describes the last change of the object along with the field manager that made it,
+// e.g. "kubectl-edit (NodeConfig eth2-vlan-104)".
+func changeAuthor(kind string, obj metav1.Object) string {
+	manager, _ := lastChange(obj)
+	if manager == "" {
+		return fmt.Sprintf("%s %s", kind, obj.GetName())
+	}
+	return fmt.Sprintf("%s (%s %s)", manager, kind, obj.GetName())
+}
+
+// lastChange returns the field manager that last changed the object, apart from its subresources, and the time of the change.
+func lastChange(obj metav1.Object) (string, time.Time) {
+	var manager string
+	var last time.Time
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Subresource != "" || entry.Time == nil || entry.Time.Time.Before(last) {
+			continue
+		}
+		manager, last = entry.Manager, entry.Time.Time
+	}
+	return manager, last
+}
+
+// setChangeAuthor records the author of a change of spec.mergedConfig on the FullConfig, which ends any rollback.
+func setChangeAuthor(fullConfig *iprulerv1.FullConfig, author string) {
+	if fullConfig.Annotations == nil {
+		fullConfig.Annotations = map[string]string{}
+	}
+	fullConfig.Annotations[iprulerv1.AuthorAnnotation] = author
+	delete(fullConfig.Annotations, iprulerv1.RolledBackAnnotation)
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// newTestFullConfigReconciler returns a FullConfigReconciler backed by a fake client holding the given objects.
+func newTestFullConfigReconciler(t *testing.T, objects ...client.Object) *FullConfigReconciler {
+	c := newTestClient(t, objects...)
+	return &FullConfigReconciler{Client: c, APIReader: c, Scheme: c.Scheme(), Log: ctrl.Log.WithName("test")}
+}
+
+// testConfig returns a config holding a single VLAN, so that the configs of different revisions tell apart.
+func testConfig(vlan string) models.ConfigModel {
+	return models.ConfigModel{Vlans: []models.VlanModel{{Name: vlan, Link: "eth0", ID: 100}}}
+}
+
+func testRevision(fullConfig string, revision int64, hash string) *iprulerv1.ConfigRevision {
+	return &iprulerv1.ConfigRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("%s-%d", fullConfig, revision),
+			Labels: map[string]string{iprulerv1.FullConfigLabel: fullConfig},
+		},
+		Spec: iprulerv1.ConfigRevisionSpec{
+			FullConfig: fullConfig,
+			Revision:   revision,
+			ConfigHash: hash,
+			Config:     testConfig(fmt.Sprintf("vlan.%d", revision)),
+		},
+	}
+}
+
+func TestRecordRevision(t *testing.T) {
+	tests := []struct {
+		name          string
+		revisions     []client.Object
+		historyLimit  int
+		hash          string
+		wantRevisions []int64
+		wantCurrent   int64
+		wantCreated   bool
+	}{
+		{
+			name:          "first revision",
+			historyLimit:  10,
+			hash:          "aaa",
+			wantRevisions: []int64{1},
+			wantCurrent:   1,
+			wantCreated:   true,
+		},
+		{
+			name:          "unchanged config",
+			revisions:     []client.Object{testRevision("test", 1, "aaa"), testRevision("test", 2, "bbb")},
+			historyLimit:  10,
+			hash:          "bbb",
+			wantRevisions: []int64{1, 2},
+			wantCurrent:   2,
+		},
+		{
+			name:          "config of an older revision is a new revision",
+			revisions:     []client.Object{testRevision("test", 1, "aaa"), testRevision("test", 2, "bbb")},
+			historyLimit:  10,
+			hash:          "aaa",
+			wantRevisions: []int64{1, 2, 3},
+			wantCurrent:   3,
+			wantCreated:   true,
+		},
+		{
+			name:          "revisions of other FullConfigs are left alone",
+			revisions:     []client.Object{testRevision("other", 1, "aaa"), testRevision("other", 2, "bbb")},
+			historyLimit:  1,
+			hash:          "bbb",
+			wantRevisions: []int64{1},
+			wantCurrent:   1,
+			wantCreated:   true,
+		},
+		{
+			name:          "oldest revisions beyond the history limit are pruned",
+			revisions:     []client.Object{testRevision("test", 1, "aaa"), testRevision("test", 2, "bbb"), testRevision("test", 3, "ccc")},
+			historyLimit:  2,
+			hash:          "ddd",
+			wantRevisions: []int64{3, 4},
+			wantCurrent:   4,
+			wantCreated:   true,
+		},
+		{
+			name:          "history limit below one keeps the latest revision",
+			revisions:     []client.Object{testRevision("test", 1, "aaa")},
+			historyLimit:  0,
+			hash:          "bbb",
+			wantRevisions: []int64{2},
+			wantCurrent:   2,
+			wantCreated:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyLimit := envirnment.ConfigRevisionHistoryLimit
+			envirnment.ConfigRevisionHistoryLimit = tt.historyLimit
+			defer func() { envirnment.ConfigRevisionHistoryLimit = historyLimit }()
+
+			ctx := context.Background()
+			fullConfig := &iprulerv1.FullConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: map[string]string{iprulerv1.AuthorAnnotation: "kubectl-edit (NodeConfig test)"},
+				},
+				Spec: iprulerv1.FullConfigSpec{MergedConfig: testConfig("vlan.new")},
+			}
+			r := newTestFullConfigReconciler(t, append(tt.revisions, fullConfig)...)
+
+			if err := r.recordRevision(ctx, fullConfig, tt.hash); err != nil {
+				t.Fatalf("recordRevision() error = %v", err)
+			}
+			if fullConfig.Status.CurrentRevision != tt.wantCurrent {
+				t.Errorf("current revision = %d, want %d", fullConfig.Status.CurrentRevision, tt.wantCurrent)
+			}
+
+			revisions, err := r.listRevisions(ctx, fullConfig)
+			if err != nil {
+				t.Fatalf("listRevisions() error = %v", err)
+			}
+			var numbers []int64
+			for _, revision := range revisions {
+				numbers = append(numbers, revision.Spec.Revision)
+			}
+			if !slices.Equal(numbers, tt.wantRevisions) {
+				t.Errorf("revisions = %v, want %v", numbers, tt.wantRevisions)
+			}
+
+			latest := revisions[len(revisions)-1]
+			if tt.wantCreated {
+				if latest.Spec.ConfigHash != tt.hash || latest.Spec.Config.Vlans[0].Name != "vlan.new" {
+					t.Errorf("new revision holds config %s %v, want %s with the merged config", latest.Spec.ConfigHash, latest.Spec.Config, tt.hash)
+				}
+				if latest.Spec.Author != "kubectl-edit (NodeConfig test)" {
+					t.Errorf("new revision author = %q, want the author of the FullConfig", latest.Spec.Author)
+				}
+				if owner := metav1.GetControllerOf(&latest); owner == nil || owner.Name != fullConfig.Name {
+					t.Errorf("new revision is controlled by %v, want the FullConfig", owner)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleRollback(t *testing.T) {
+	tests := []struct {
+		name           string
+		requested      string
+		wantVlan       string
+		wantRolledBack string
+	}{
+		{name: "known revision", requested: "2", wantVlan: "vlan.2", wantRolledBack: "2"},
+		{name: "oldest revision", requested: "1", wantVlan: "vlan.1", wantRolledBack: "1"},
+		{name: "unknown revision", requested: "5", wantVlan: "vlan.new"},
+		{name: "not a revision number", requested: "latest", wantVlan: "vlan.new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fullConfig := &iprulerv1.FullConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						iprulerv1.AuthorAnnotation:   "kubectl-edit (NodeConfig test)",
+						iprulerv1.RollbackAnnotation: tt.requested,
+					},
+				},
+				Spec: iprulerv1.FullConfigSpec{MergedConfig: testConfig("vlan.new")},
+			}
+			r := newTestFullConfigReconciler(t, testRevision("test", 1, "aaa"), testRevision("test", 2, "bbb"),
+				testRevision("test", 3, "ccc"), testRevision("other", 5, "eee"), fullConfig)
+
+			if err := r.handleRollback(ctx, fullConfig); err != nil {
+				t.Fatalf("handleRollback() error = %v", err)
+			}
+
+			updated := &iprulerv1.FullConfig{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(fullConfig), updated); err != nil {
+				t.Fatalf("failed to get the FullConfig: %v", err)
+			}
+			if vlan := updated.Spec.MergedConfig.Vlans[0].Name; vlan != tt.wantVlan {
+				t.Errorf("merged config has %s, want %s", vlan, tt.wantVlan)
+			}
+			if _, ok := updated.Annotations[iprulerv1.RollbackAnnotation]; ok {
+				t.Errorf("rollback annotation is left on the FullConfig")
+			}
+			if rolledBack := updated.Annotations[iprulerv1.RolledBackAnnotation]; rolledBack != tt.wantRolledBack {
+				t.Errorf("rolled back annotation = %q, want %q", rolledBack, tt.wantRolledBack)
+			}
+			wantAuthor := "kubectl-edit (NodeConfig test)"
+			if tt.wantRolledBack != "" {
+				wantAuthor = "rollback to revision " + tt.wantRolledBack
+			}
+			if author := updated.Annotations[iprulerv1.AuthorAnnotation]; author != wantAuthor {
+				t.Errorf("author = %q, want %q", author, wantAuthor)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// minRolloutBatchInterval is the shortest time between two batches of a rollout.
+const minRolloutBatchInterval = time.Second
+
+// plannedInjection is a config to be injected into the node at the given index of the targets.
+type plannedInjection struct {
+	node            int
+	config          *models.ConfigModel
+	hash            string
+	healthCheckPath string
+	canary          bool
+	rollback        bool
+}
+
+// planRollout picks, out of the candidate nodes (indexes of targets and nodeStatuses) the merged config with the given hash is due for,
+// those it is injected into right now according to the rollout strategy of the FullConfig, and records the rollout in its status.
+// Nodes that never had a config applied, or already have this one, are never held back.
+// Failed canaries are rolled back to the last known-good config instead.
+// It also returns how long to wait before the next batch, or zero if there is nothing to wait for.
+func planRollout(fullConfig *iprulerv1.FullConfig, targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus, candidates []int, hash string, specChanged bool, now time.Time) ([]plannedInjection, time.Duration) {
+	strategy := fullConfig.Spec.EffectiveRolloutStrategy()
+	mergedConfig := &fullConfig.Spec.MergedConfig
+	if strategy == nil {
+		fullConfig.Status.Rollout = nil
+		planned := make([]plannedInjection, 0, len(candidates))
+		for _, i := range candidates {
+			planned = append(planned, plannedInjection{node: i, config: mergedConfig, hash: hash})
+		}
+		return planned, 0
+	}
+
+	// a new config, or any change of the spec of a halted or rolled back rollout, starts the rollout over
+	rollout := fullConfig.Status.Rollout
+	if rollout == nil || rollout.ConfigHash != hash ||
+		(specChanged && (rollout.Phase == iprulerv1.RolloutPhaseHalted || rollout.Phase == iprulerv1.RolloutPhaseRolledBack)) {
+		rollout = &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseProgressing}
+		if strategy.Canary != nil {
+			rollout.Phase = iprulerv1.RolloutPhaseCanary
+		}
+		fullConfig.Status.Rollout = rollout
+	}
+
+	canaries := canaryNodes(strategy.Canary, targets)
+	rollout.CanaryNodes = nil
+	for _, i := range canaries {
+		rollout.CanaryNodes = append(rollout.CanaryNodes, targets[i].node.Name)
+	}
+
+	failing := 0
+	for i := range nodeStatuses {
+		if failingNode(&nodeStatuses[i], hash) {
+			failing++
+		}
+	}
+
+	advanceCanary(fullConfig, nodeStatuses, hash)
+
+	var planned []plannedInjection
+	var retries, outdated, waiting []int
+	isCanary := map[int]bool{}
+	for _, i := range canaries {
+		isCanary[i] = true
+	}
+	for _, i := range candidates {
+		switch {
+		case rollout.Phase == iprulerv1.RolloutPhaseRolledBack && isCanary[i]:
+			// the canaries are rolled back below
+		case !outdatedNode(&nodeStatuses[i], hash):
+			planned = append(planned, plannedInjection{node: i, config: mergedConfig, hash: hash})
+		case nodeStatuses[i].Attempts > 0:
+			retries = append(retries, i)
+		default:
+			outdated = append(outdated, i)
+		}
+	}
+
+	var requeueAfter time.Duration
+	switch {
+	case rollout.Phase == iprulerv1.RolloutPhaseRolledBack:
+		planned = append(planned, planRollback(fullConfig, targets, nodeStatuses, canaries)...)
+		waiting = append(retries, outdated...)
+	case rollout.Phase == iprulerv1.RolloutPhaseHalted:
+		waiting = append(retries, outdated...)
+	case strategy.HaltOnFailure && failing > 0:
+		rollout.Phase = iprulerv1.RolloutPhaseHalted
+		waiting = append(retries, outdated...)
+	case strategy.Paused:
+		if rollout.Phase != iprulerv1.RolloutPhaseCanary {
+			rollout.Phase = iprulerv1.RolloutPhasePaused
+		}
+		waiting = append(retries, outdated...)
+	case rollout.Phase == iprulerv1.RolloutPhaseCanary:
+		// only the canaries get the new config, all of them at once
+		var batch []int
+		for _, i := range append(retries, outdated...) {
+			if isCanary[i] {
+				batch = append(batch, i)
+				planned = append(planned, plannedInjection{node: i, config: mergedConfig, hash: hash, healthCheckPath: strategy.Canary.HealthCheckPath, canary: true})
+			} else {
+				waiting = append(waiting, i)
+			}
+		}
+		if len(batch) > 0 {
+			rollout.LastBatchTime = &metav1.Time{Time: now}
+		}
+	default:
+		rollout.Phase = iprulerv1.RolloutPhaseProgressing
+		// the nodes of the previous batches that failed are retried with their own backoff
+		for _, i := range retries {
+			planned = append(planned, plannedInjection{node: i, config: mergedConfig, hash: hash})
+		}
+
+		var pause time.Duration
+		if strategy.PauseBetweenBatches != nil {
+			pause = strategy.PauseBetweenBatches.Duration
+		}
+		if len(outdated) > 0 && rollout.LastBatchTime != nil && now.Before(rollout.LastBatchTime.Add(pause)) {
+			waiting = outdated
+			requeueAfter = rollout.LastBatchTime.Add(pause).Sub(now)
+			break
+		}
+
+		batch := rolloutBatchSize(strategy, len(nodeStatuses)) - failing
+		if batch < 0 {
+			batch = 0
+		}
+		if batch > len(outdated) {
+			batch = len(outdated)
+		}
+		for _, i := range outdated[:batch] {
+			planned = append(planned, plannedInjection{node: i, config: mergedConfig, hash: hash})
+		}
+		waiting = outdated[batch:]
+		if batch > 0 {
+			rollout.LastBatchTime = &metav1.Time{Time: now}
+			if len(waiting) > 0 {
+				requeueAfter = max(pause, minRolloutBatchInterval)
+			}
+		}
+	}
+
+	for _, i := range waiting {
+		nodeStatuses[i].Message = "Waiting for the rollout of the new config"
+	}
+	sort.Slice(planned, func(i, j int) bool {
+		return planned[i].node < planned[j].node
+	})
+	return planned, requeueAfter
+}
+
+// planRollback plans the injection of the last known-good config into the canaries that don't have it yet.
+func planRollback(fullConfig *iprulerv1.FullConfig, targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus, canaries []int) []plannedInjection {
+	var planned []plannedInjection
+	for _, i := range canaries {
+		nodeStatus := &nodeStatuses[i]
+		if targets[i].pod == nil || (nodeStatus.Applied && nodeStatus.ConfigHash == fullConfig.Status.LastKnownGoodHash) {
+			continue
+		}
+		if !nodeStatus.Applied && nodeStatus.Attempts >= globalAgentManager.MaxAttempts {
+			// the rollback failed as well, it's up to the user to fix the config
+			continue
+		}
+		planned = append(planned, plannedInjection{
+			node:     i,
+			config:   fullConfig.Status.LastKnownGoodConfig,
+			hash:     fullConfig.Status.LastKnownGoodHash,
+			rollback: true,
+		})
+	}
+	return planned
+}
+
+// canaryNodes returns the indexes of the canary nodes out of the targets, which are sorted by name.
+// Only nodes with a ready agent pod are picked, as a canary that can't be injected into would hold the rollout forever.
+func canaryNodes(canary *iprulerv1.CanaryStrategy, targets []agentTarget) []int {
+	if canary == nil {
+		return nil
+	}
+
+	var ready []int
+	for i := range targets {
+		if targets[i].pod != nil {
+			ready = append(ready, i)
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+
+	var canaries []int
+	if len(canary.NodeSelector) > 0 {
+		for _, i := range ready {
+			if nodeMatchesSelector(&targets[i].node, canary.NodeSelector) {
+				canaries = append(canaries, i)
+			}
+		}
+		return canaries
+	}
+
+	count := 1
+	if canary.Percentage > 0 {
+		count = (len(ready)*canary.Percentage + 99) / 100
+	}
+	return ready[:min(count, len(ready))]
+}
+
+// advanceCanary moves a rollout in the canary phase on, once all the canaries applied the new config
+// or as soon as one of them failed. In the latter case the canaries are rolled back to the last known-good config,
+// or the rollout is halted if there is none yet. It reports whether the phase has changed.
+func advanceCanary(fullConfig *iprulerv1.FullConfig, nodeStatuses []iprulerv1.NodeStatus, hash string) bool {
+	rollout := fullConfig.Status.Rollout
+	if rollout.Phase != iprulerv1.RolloutPhaseCanary {
+		return false
+	}
+
+	failed, applied := false, 0
+	for i := range nodeStatuses {
+		if !slices.Contains(rollout.CanaryNodes, nodeStatuses[i].NodeName) {
+			continue
+		}
+		if canaryFailed(&nodeStatuses[i]) {
+			failed = true
+		} else if nodeStatuses[i].Applied && nodeStatuses[i].ConfigHash == hash {
+			applied++
+		}
+	}
+
+	switch {
+	case failed && fullConfig.Status.LastKnownGoodConfig == nil:
+		rollout.Phase = iprulerv1.RolloutPhaseHalted
+	case failed:
+		rollout.Phase = iprulerv1.RolloutPhaseRolledBack
+	case applied == len(rollout.CanaryNodes):
+		rollout.Phase = iprulerv1.RolloutPhaseProgressing
+	default:
+		return false
+	}
+	return true
+}
+
+// updateRolloutStatus updates the progress of the rollout of the merged config with the given hash from the per-node delivery status.
+// It returns how long to wait before moving on to the next phase of the rollout, or zero if there is nothing to move on to.
+func updateRolloutStatus(fullConfig *iprulerv1.FullConfig, nodeStatuses []iprulerv1.NodeStatus, hash string) time.Duration {
+	rollout := fullConfig.Status.Rollout
+	strategy := fullConfig.Spec.EffectiveRolloutStrategy()
+	if rollout == nil || strategy == nil {
+		return 0
+	}
+
+	var requeueAfter time.Duration
+	if advanceCanary(fullConfig, nodeStatuses, hash) {
+		requeueAfter = minRolloutBatchInterval
+	}
+
+	updated, outdated := 0, 0
+	var failedNodes []string
+	for i, nodeStatus := range nodeStatuses {
+		if nodeStatus.Applied && nodeStatus.ConfigHash == hash {
+			updated++
+		}
+		if outdatedNode(&nodeStatuses[i], hash) {
+			outdated++
+		}
+		if failingNode(&nodeStatuses[i], hash) {
+			failedNodes = append(failedNodes, nodeStatus.NodeName)
+		}
+	}
+	rollout.UpdatedNodes = updated
+	rollout.TotalNodes = len(nodeStatuses)
+
+	if rollout.Phase == iprulerv1.RolloutPhaseProgressing {
+		if strategy.HaltOnFailure && len(failedNodes) > 0 {
+			rollout.Phase = iprulerv1.RolloutPhaseHalted
+		} else if outdated == 0 {
+			rollout.Phase = iprulerv1.RolloutPhaseCompleted
+		}
+	}
+
+	progress := fmt.Sprintf("%d/%d nodes are updated", rollout.UpdatedNodes, rollout.TotalNodes)
+	switch rollout.Phase {
+	case iprulerv1.RolloutPhaseCanary:
+		rollout.Message = fmt.Sprintf("Trying the config on canary nodes %s, %s", strings.Join(rollout.CanaryNodes, ", "), progress)
+	case iprulerv1.RolloutPhaseRolledBack:
+		rollout.Message = fmt.Sprintf("Canary nodes %s are rolled back to the last known-good config %s, %s",
+			strings.Join(rollout.CanaryNodes, ", "), fullConfig.Status.LastKnownGoodHash, progress)
+	case iprulerv1.RolloutPhaseHalted:
+		rollout.Message = "The rollout is halted because a node failed to apply the config, " + progress
+		if len(failedNodes) > 0 {
+			rollout.Message = fmt.Sprintf("The rollout is halted because nodes %s failed to apply the config, %s", strings.Join(failedNodes, ", "), progress)
+		}
+	case iprulerv1.RolloutPhasePaused:
+		rollout.Message = "The rollout is paused, " + progress
+	case iprulerv1.RolloutPhaseCompleted:
+		rollout.Message = "No node is left with an older config, " + progress
+	default:
+		rollout.Message = progress
+		if len(failedNodes) > 0 {
+			rollout.Message = fmt.Sprintf("%s, nodes %s failed to apply the config", progress, strings.Join(failedNodes, ", "))
+		}
+	}
+
+	return requeueAfter
+}
+
+// rolloutBatchSize returns the number of nodes, out of the given total, a batch of the rollout may have in flight.
+func rolloutBatchSize(strategy *iprulerv1.RolloutStrategy, total int) int {
+	maxUnavailable := intstr.FromInt32(1)
+	if strategy.MaxUnavailable != nil {
+		maxUnavailable = *strategy.MaxUnavailable
+	}
+	size, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, total, true)
+	if err != nil || size < 1 {
+		return 1
+	}
+	return size
+}
+
+// outdatedNode reports whether the node has an older config applied, i.e. whether it is subject to the rollout of the new one.
+func outdatedNode(nodeStatus *iprulerv1.NodeStatus, hash string) bool {
+	return nodeStatus.ConfigHash != "" && nodeStatus.ConfigHash != hash
+}
+
+// canaryFailed reports whether the agent of the canary node failed to apply the new config or to report healthy afterwards.
+func canaryFailed(nodeStatus *iprulerv1.NodeStatus) bool {
+	return nodeStatus.AgentPod != "" && !nodeStatus.Applied && nodeStatus.Attempts > 0
+}
+
+// failingNode reports whether the node failed to apply the new config.
+func failingNode(nodeStatus *iprulerv1.NodeStatus, hash string) bool {
+	return outdatedNode(nodeStatus, hash) && !nodeStatus.Applied && nodeStatus.Attempts > 0
+}
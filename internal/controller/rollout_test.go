@@ -0,0 +1,521 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// The rollout is planned by pure functions, so it is tested without the envtest suite.
+
+// testTargets returns a target with a ready agent pod for each of the given node names.
+func testTargets(names ...string) []agentTarget {
+	targets := make([]agentTarget, 0, len(names))
+	for _, name := range names {
+		targets = append(targets, agentTarget{
+			node: corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}},
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ipruler-agent-" + name}},
+		})
+	}
+	return targets
+}
+
+// testNodeStatuses returns the status of each of the targets, with the config with the given hash applied.
+func testNodeStatuses(targets []agentTarget, hash string) []iprulerv1.NodeStatus {
+	nodeStatuses := make([]iprulerv1.NodeStatus, 0, len(targets))
+	for _, target := range targets {
+		nodeStatuses = append(nodeStatuses, iprulerv1.NodeStatus{
+			NodeName:   target.node.Name,
+			AgentPod:   target.pod.Name,
+			ConfigHash: hash,
+			Applied:    true,
+		})
+	}
+	return nodeStatuses
+}
+
+func plannedNodes(planned []plannedInjection) []int {
+	nodes := []int{}
+	for _, injection := range planned {
+		nodes = append(nodes, injection.node)
+	}
+	return nodes
+}
+
+func TestRolloutBatchSize(t *testing.T) {
+	intOrPercent := func(value intstr.IntOrString) *intstr.IntOrString { return &value }
+	tests := []struct {
+		name           string
+		maxUnavailable *intstr.IntOrString
+		total          int
+		want           int
+	}{
+		{name: "defaults to one node", total: 10, want: 1},
+		{name: "number of nodes", maxUnavailable: intOrPercent(intstr.FromInt32(3)), total: 10, want: 3},
+		{name: "percentage is rounded up", maxUnavailable: intOrPercent(intstr.FromString("25%")), total: 10, want: 3},
+		{name: "whole percentage", maxUnavailable: intOrPercent(intstr.FromString("100%")), total: 4, want: 4},
+		{name: "zero nodes is one node", maxUnavailable: intOrPercent(intstr.FromInt32(0)), total: 10, want: 1},
+		{name: "zero percentage is one node", maxUnavailable: intOrPercent(intstr.FromString("0%")), total: 10, want: 1},
+		{name: "invalid percentage is one node", maxUnavailable: intOrPercent(intstr.FromString("half")), total: 10, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := &iprulerv1.RolloutStrategy{MaxUnavailable: tt.maxUnavailable}
+			if got := rolloutBatchSize(strategy, tt.total); got != tt.want {
+				t.Errorf("rolloutBatchSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanaryNodes(t *testing.T) {
+	labeled := testTargets("node-a", "node-b", "node-c", "node-d")
+	labeled[1].node.Labels = map[string]string{"canary": "true"}
+	labeled[3].node.Labels = map[string]string{"canary": "true", "zone": "b"}
+	withoutAgent := testTargets("node-a", "node-b", "node-c", "node-d")
+	withoutAgent[0].pod = nil
+	withoutAgent[0].node.Labels = map[string]string{"canary": "true"}
+	withoutAgent[1].node.Labels = map[string]string{"canary": "true"}
+
+	tests := []struct {
+		name    string
+		canary  *iprulerv1.CanaryStrategy
+		targets []agentTarget
+		want    []int
+	}{
+		{name: "no canary", targets: testTargets("node-a", "node-b"), want: nil},
+		{name: "no targets", canary: &iprulerv1.CanaryStrategy{}, want: nil},
+		{name: "defaults to the first node", canary: &iprulerv1.CanaryStrategy{}, targets: testTargets("node-a", "node-b"), want: []int{0}},
+		{name: "percentage is rounded up", canary: &iprulerv1.CanaryStrategy{Percentage: 30}, targets: testTargets("node-a", "node-b", "node-c", "node-d", "node-e"), want: []int{0, 1}},
+		{name: "small percentage is one node", canary: &iprulerv1.CanaryStrategy{Percentage: 1}, targets: testTargets("node-a", "node-b", "node-c"), want: []int{0}},
+		{name: "whole percentage", canary: &iprulerv1.CanaryStrategy{Percentage: 100}, targets: testTargets("node-a", "node-b", "node-c"), want: []int{0, 1, 2}},
+		{name: "node selector", canary: &iprulerv1.CanaryStrategy{NodeSelector: map[string]string{"canary": "true"}}, targets: labeled, want: []int{1, 3}},
+		{name: "node selector takes precedence over percentage", canary: &iprulerv1.CanaryStrategy{NodeSelector: map[string]string{"zone": "b"}, Percentage: 100}, targets: labeled, want: []int{3}},
+		{name: "node selector matching no node", canary: &iprulerv1.CanaryStrategy{NodeSelector: map[string]string{"zone": "c"}}, targets: labeled, want: nil},
+		{name: "nodes without an agent pod are never canaries", canary: &iprulerv1.CanaryStrategy{}, targets: withoutAgent, want: []int{1}},
+		{name: "percentage of the nodes with an agent pod", canary: &iprulerv1.CanaryStrategy{Percentage: 50}, targets: withoutAgent, want: []int{1, 2}},
+		{name: "node selector skips nodes without an agent pod", canary: &iprulerv1.CanaryStrategy{NodeSelector: map[string]string{"canary": "true"}}, targets: withoutAgent, want: []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canaryNodes(tt.canary, tt.targets); !slices.Equal(got, tt.want) {
+				t.Errorf("canaryNodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	const hash = "new"
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	maxUnavailable := intstr.FromInt32(2)
+	failing := func(nodeStatuses []iprulerv1.NodeStatus) {
+		nodeStatuses[0].Applied = false
+		nodeStatuses[0].Attempts = 1
+	}
+
+	tests := []struct {
+		name         string
+		strategy     *iprulerv1.RolloutStrategy
+		rollout      *iprulerv1.RolloutStatus
+		setup        func(nodeStatuses []iprulerv1.NodeStatus)
+		withoutAgent []int
+		specChanged  bool
+		wantPlanned  []int
+		wantCanary   bool
+		wantPhase    iprulerv1.RolloutPhase
+		wantRequeue  time.Duration
+		wantWaiting  []int
+		wantBatch    bool
+		wantNoStatus bool
+	}{
+		{
+			name:         "without a strategy every candidate is planned",
+			wantPlanned:  []int{0, 1, 2, 3},
+			wantNoStatus: true,
+		},
+		{
+			name:        "one node at a time by default",
+			strategy:    &iprulerv1.RolloutStrategy{},
+			wantPlanned: []int{0},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: minRolloutBatchInterval,
+			wantWaiting: []int{1, 2, 3},
+			wantBatch:   true,
+		},
+		{
+			name:        "batches of maxUnavailable nodes",
+			strategy:    &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable, PauseBetweenBatches: &metav1.Duration{Duration: time.Minute}},
+			wantPlanned: []int{0, 1},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: time.Minute,
+			wantWaiting: []int{2, 3},
+			wantBatch:   true,
+		},
+		{
+			name:     "next batch waits for the pause",
+			strategy: &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable, PauseBetweenBatches: &metav1.Duration{Duration: time.Minute}},
+			rollout: &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseProgressing,
+				LastBatchTime: &metav1.Time{Time: now.Add(-10 * time.Second)}},
+			wantPlanned: []int{},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: 50 * time.Second,
+			wantWaiting: []int{0, 1, 2, 3},
+		},
+		{
+			name:     "next batch once the pause is over",
+			strategy: &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable, PauseBetweenBatches: &metav1.Duration{Duration: time.Minute}},
+			rollout: &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseProgressing,
+				LastBatchTime: &metav1.Time{Time: now.Add(-time.Minute)}},
+			wantPlanned: []int{0, 1},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: time.Minute,
+			wantWaiting: []int{2, 3},
+			wantBatch:   true,
+		},
+		{
+			name:        "failing nodes are retried and take up the batch",
+			strategy:    &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable},
+			setup:       failing,
+			wantPlanned: []int{0, 1},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: minRolloutBatchInterval,
+			wantWaiting: []int{2, 3},
+			wantBatch:   true,
+		},
+		{
+			name:        "halts on failure",
+			strategy:    &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable, HaltOnFailure: true},
+			setup:       failing,
+			wantPlanned: []int{},
+			wantPhase:   iprulerv1.RolloutPhaseHalted,
+			wantWaiting: []int{0, 1, 2, 3},
+		},
+		{
+			name:        "halted rollout stays halted",
+			strategy:    &iprulerv1.RolloutStrategy{},
+			rollout:     &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseHalted},
+			wantPlanned: []int{},
+			wantPhase:   iprulerv1.RolloutPhaseHalted,
+			wantWaiting: []int{0, 1, 2, 3},
+		},
+		{
+			name:        "halted rollout starts over on a spec change",
+			strategy:    &iprulerv1.RolloutStrategy{},
+			rollout:     &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseHalted},
+			specChanged: true,
+			wantPlanned: []int{0},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: minRolloutBatchInterval,
+			wantWaiting: []int{1, 2, 3},
+			wantBatch:   true,
+		},
+		{
+			name:        "paused",
+			strategy:    &iprulerv1.RolloutStrategy{Paused: true},
+			wantPlanned: []int{},
+			wantPhase:   iprulerv1.RolloutPhasePaused,
+			wantWaiting: []int{0, 1, 2, 3},
+		},
+		{
+			name:     "new nodes and up to date nodes are never held back",
+			strategy: &iprulerv1.RolloutStrategy{Paused: true},
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[1].ConfigHash = ""
+				nodeStatuses[1].Applied = false
+				nodeStatuses[2].ConfigHash = hash
+			},
+			wantPlanned: []int{1, 2},
+			wantPhase:   iprulerv1.RolloutPhasePaused,
+			wantWaiting: []int{0, 3},
+		},
+		{
+			name:        "only the canaries in the canary phase",
+			strategy:    &iprulerv1.RolloutStrategy{Canary: &iprulerv1.CanaryStrategy{Percentage: 50, HealthCheckPath: "/healthz"}},
+			wantPlanned: []int{0, 1},
+			wantCanary:  true,
+			wantPhase:   iprulerv1.RolloutPhaseCanary,
+			wantWaiting: []int{2, 3},
+			wantBatch:   true,
+		},
+		{
+			name:     "paused canary phase",
+			strategy: &iprulerv1.RolloutStrategy{Paused: true, Canary: &iprulerv1.CanaryStrategy{}},
+			// a paused rollout keeps its canary phase
+			wantPlanned: []int{},
+			wantPhase:   iprulerv1.RolloutPhaseCanary,
+			wantWaiting: []int{0, 1, 2, 3},
+		},
+		{
+			name:     "promoted once the canaries applied the config",
+			strategy: &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable, Canary: &iprulerv1.CanaryStrategy{}},
+			rollout:  &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseCanary},
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].ConfigHash = hash
+			},
+			wantPlanned: []int{0, 1, 2},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantRequeue: minRolloutBatchInterval,
+			wantWaiting: []int{3},
+			wantBatch:   true,
+		},
+		{
+			name:         "canaries are picked out of the nodes with an agent pod",
+			strategy:     &iprulerv1.RolloutStrategy{Canary: &iprulerv1.CanaryStrategy{}},
+			withoutAgent: []int{0},
+			wantPlanned:  []int{1},
+			wantCanary:   true,
+			wantPhase:    iprulerv1.RolloutPhaseCanary,
+			wantWaiting:  []int{2, 3},
+			wantBatch:    true,
+		},
+		{
+			name:         "node without an agent pod doesn't hold back the promotion",
+			strategy:     &iprulerv1.RolloutStrategy{MaxUnavailable: &maxUnavailable, Canary: &iprulerv1.CanaryStrategy{}},
+			rollout:      &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: iprulerv1.RolloutPhaseCanary},
+			withoutAgent: []int{0},
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[1].ConfigHash = hash
+			},
+			wantPlanned: []int{1, 2, 3},
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+			wantBatch:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := testTargets("node-a", "node-b", "node-c", "node-d")
+			nodeStatuses := testNodeStatuses(targets, "old")
+			if tt.setup != nil {
+				tt.setup(nodeStatuses)
+			}
+			// the config is due only for the nodes with an agent pod
+			var candidates []int
+			for i := range targets {
+				if slices.Contains(tt.withoutAgent, i) {
+					targets[i].pod = nil
+				} else {
+					candidates = append(candidates, i)
+				}
+			}
+			fullConfig := &iprulerv1.FullConfig{
+				Spec:   iprulerv1.FullConfigSpec{NodeRolloutStrategy: tt.strategy},
+				Status: iprulerv1.FullConfigStatus{Rollout: tt.rollout},
+			}
+
+			planned, requeueAfter := planRollout(fullConfig, targets, nodeStatuses, candidates, hash, tt.specChanged, now)
+
+			if got := plannedNodes(planned); !slices.Equal(got, tt.wantPlanned) {
+				t.Errorf("planned nodes = %v, want %v", got, tt.wantPlanned)
+			}
+			for _, injection := range planned {
+				if injection.hash != hash || injection.config != &fullConfig.Spec.MergedConfig {
+					t.Errorf("node %d is planned with config %s, want the merged config %s", injection.node, injection.hash, hash)
+				}
+				wantPath := ""
+				if tt.wantCanary {
+					wantPath = tt.strategy.Canary.HealthCheckPath
+				}
+				if injection.canary != tt.wantCanary || injection.healthCheckPath != wantPath {
+					t.Errorf("node %d is planned as canary %t with health check %q, want %t with %q",
+						injection.node, injection.canary, injection.healthCheckPath, tt.wantCanary, wantPath)
+				}
+			}
+			if requeueAfter != tt.wantRequeue {
+				t.Errorf("requeueAfter = %v, want %v", requeueAfter, tt.wantRequeue)
+			}
+			var waiting []int
+			for i := range nodeStatuses {
+				if nodeStatuses[i].Message != "" {
+					waiting = append(waiting, i)
+				}
+			}
+			if !slices.Equal(waiting, tt.wantWaiting) {
+				t.Errorf("waiting nodes = %v, want %v", waiting, tt.wantWaiting)
+			}
+
+			rollout := fullConfig.Status.Rollout
+			if tt.wantNoStatus {
+				if rollout != nil {
+					t.Errorf("rollout status = %+v, want none", rollout)
+				}
+				return
+			}
+			if rollout == nil {
+				t.Fatalf("rollout status is missing")
+			}
+			if rollout.ConfigHash != hash {
+				t.Errorf("rollout config hash = %q, want %q", rollout.ConfigHash, hash)
+			}
+			if rollout.Phase != tt.wantPhase {
+				t.Errorf("rollout phase = %q, want %q", rollout.Phase, tt.wantPhase)
+			}
+			if batchStarted := rollout.LastBatchTime != nil && rollout.LastBatchTime.Time.Equal(now); batchStarted != tt.wantBatch {
+				t.Errorf("rollout last batch time = %v, want a batch started now %t", rollout.LastBatchTime, tt.wantBatch)
+			}
+		})
+	}
+}
+
+func TestAdvanceCanary(t *testing.T) {
+	const hash = "new"
+	tests := []struct {
+		name          string
+		phase         iprulerv1.RolloutPhase
+		lastKnownGood bool
+		setup         func(nodeStatuses []iprulerv1.NodeStatus)
+		wantChanged   bool
+		wantPhase     iprulerv1.RolloutPhase
+	}{
+		{
+			name:      "not in the canary phase",
+			phase:     iprulerv1.RolloutPhaseProgressing,
+			setup:     func(nodeStatuses []iprulerv1.NodeStatus) {},
+			wantPhase: iprulerv1.RolloutPhaseProgressing,
+		},
+		{
+			name:  "canaries still applying the config",
+			phase: iprulerv1.RolloutPhaseCanary,
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].ConfigHash = hash
+			},
+			wantPhase: iprulerv1.RolloutPhaseCanary,
+		},
+		{
+			name:  "promoted once all the canaries applied the config and reported healthy",
+			phase: iprulerv1.RolloutPhaseCanary,
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].ConfigHash = hash
+				nodeStatuses[1].ConfigHash = hash
+			},
+			wantChanged: true,
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+		},
+		{
+			name:  "other nodes failing don't hold back the promotion",
+			phase: iprulerv1.RolloutPhaseCanary,
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].ConfigHash = hash
+				nodeStatuses[1].ConfigHash = hash
+				nodeStatuses[2].Applied = false
+				nodeStatuses[2].Attempts = 1
+			},
+			wantChanged: true,
+			wantPhase:   iprulerv1.RolloutPhaseProgressing,
+		},
+		{
+			name:          "rolled back when a canary failed the health check",
+			phase:         iprulerv1.RolloutPhaseCanary,
+			lastKnownGood: true,
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].ConfigHash = hash
+				nodeStatuses[1].Applied = false
+				nodeStatuses[1].Attempts = 1
+			},
+			wantChanged: true,
+			wantPhase:   iprulerv1.RolloutPhaseRolledBack,
+		},
+		{
+			name:  "halted when a canary failed and there is no known-good config",
+			phase: iprulerv1.RolloutPhaseCanary,
+			setup: func(nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[1].Applied = false
+				nodeStatuses[1].Attempts = 1
+			},
+			wantChanged: true,
+			wantPhase:   iprulerv1.RolloutPhaseHalted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeStatuses := testNodeStatuses(testTargets("node-a", "node-b", "node-c"), "old")
+			tt.setup(nodeStatuses)
+			fullConfig := &iprulerv1.FullConfig{
+				Status: iprulerv1.FullConfigStatus{
+					Rollout: &iprulerv1.RolloutStatus{ConfigHash: hash, Phase: tt.phase, CanaryNodes: []string{"node-a", "node-b"}},
+				},
+			}
+			if tt.lastKnownGood {
+				fullConfig.Status.LastKnownGoodHash = "old"
+				fullConfig.Status.LastKnownGoodConfig = &models.ConfigModel{}
+			}
+
+			if changed := advanceCanary(fullConfig, nodeStatuses, hash); changed != tt.wantChanged {
+				t.Errorf("advanceCanary() = %t, want %t", changed, tt.wantChanged)
+			}
+			if phase := fullConfig.Status.Rollout.Phase; phase != tt.wantPhase {
+				t.Errorf("rollout phase = %q, want %q", phase, tt.wantPhase)
+			}
+		})
+	}
+}
+
+func TestPlanRollback(t *testing.T) {
+	maxAttempts := globalAgentManager.MaxAttempts
+	tests := []struct {
+		name        string
+		setup       func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus)
+		wantPlanned []int
+	}{
+		{
+			name: "failed canaries are rolled back",
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[1].Applied = false
+				nodeStatuses[1].Attempts = 1
+			},
+			wantPlanned: []int{0, 1},
+		},
+		{
+			name: "canaries with the known-good config are left alone",
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].ConfigHash = "good"
+			},
+			wantPlanned: []int{1},
+		},
+		{
+			name: "canaries without an agent pod are left alone",
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				targets[0].pod = nil
+			},
+			wantPlanned: []int{1},
+		},
+		{
+			name: "failed rollbacks are given up after the max attempts",
+			setup: func(targets []agentTarget, nodeStatuses []iprulerv1.NodeStatus) {
+				nodeStatuses[0].Applied = false
+				nodeStatuses[0].Attempts = maxAttempts
+				nodeStatuses[1].Applied = false
+				nodeStatuses[1].Attempts = maxAttempts - 1
+			},
+			wantPlanned: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := testTargets("node-a", "node-b", "node-c")
+			nodeStatuses := testNodeStatuses(targets, "new")
+			tt.setup(targets, nodeStatuses)
+			fullConfig := &iprulerv1.FullConfig{
+				Status: iprulerv1.FullConfigStatus{LastKnownGoodHash: "good", LastKnownGoodConfig: &models.ConfigModel{}},
+			}
+
+			planned := planRollback(fullConfig, targets, nodeStatuses, []int{0, 1})
+
+			if got := plannedNodes(planned); !slices.Equal(got, tt.wantPlanned) {
+				t.Errorf("planned nodes = %v, want %v", got, tt.wantPlanned)
+			}
+			for _, injection := range planned {
+				if !injection.rollback || injection.hash != "good" || injection.config != fullConfig.Status.LastKnownGoodConfig {
+					t.Errorf("node %d is planned with config %s, want a rollback to the known-good config", injection.node, injection.hash)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+// SecretReconciler triggers the FullConfigs referring to a Secret when it changes, so that rotated keys are injected
+// into the agents right away. Only the Secrets of the namespace the Secrets are read from are watched.
+type SecretReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+}
+
+func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	fullConfigList := &iprulerv1.FullConfigList{}
+	if err := r.Client.List(ctx, fullConfigList); err != nil {
+		r.Log.Error(err, "Failed to List FullConfig")
+		return ctrl.Result{}, err
+	}
+
+	// the Secret is resolved again when the FullConfig is injected, whether it has changed or is gone
+	for _, fullConfig := range fullConfigList.Items {
+		if !refersToSecret(&fullConfig.Spec.MergedConfig, req.NamespacedName) {
+			continue
+		}
+		if err := triggerFullConfig(ctx, r.Client, &fullConfig); err != nil && apierrors.IsConflict(err) {
+			r.Log.Info("Conflict in resource when updating lastUpdateTrigger annotation. The given FullConfig has been changed", "Name", fullConfig.Name)
+			return ctrl.Result{}, err
+		} else if err != nil {
+			r.Log.Error(err, "Failed to update FullConfig on lastUpdateTrigger annotation", "Name", fullConfig.Name)
+			return ctrl.Result{}, err
+		}
+		r.Log.Info("Updated FullConfig on lastUpdateTrigger because its Secret changed", "Name", fullConfig.Name, "Secret", req.NamespacedName)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("secret").
+		For(&corev1.Secret{}).
+		WithEventFilter(predicate.Funcs{
+			CreateFunc: func(e event.CreateEvent) bool {
+				return e.Object.GetNamespace() == envirnment.SecretsNamespace
+			},
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				oldSecret := e.ObjectOld.(*corev1.Secret)
+				newSecret := e.ObjectNew.(*corev1.Secret)
+				return newSecret.Namespace == envirnment.SecretsNamespace && !reflect.DeepEqual(oldSecret.Data, newSecret.Data)
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return e.Object.GetNamespace() == envirnment.SecretsNamespace
+			},
+			GenericFunc: func(e event.GenericEvent) bool {
+				return false
+			},
+		}).
+		Complete(r)
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// resolveSecrets fills in the secrets the config refers to, i.e. the private keys of its WireGuard links.
+// It's called on the copy of the config about to be injected, so that the secrets are never stored in the FullConfig.
+// The Secrets are read directly from the API server, and only from the namespace the operator is configured with,
+// so that the authors of the configs can't have the Secrets of any other namespace sent to the agents.
+func (r *FullConfigReconciler) resolveSecrets(ctx context.Context, config *models.ConfigModel) error {
+	for i := range config.Links {
+		wireguard := config.Links[i].Wireguard
+		if wireguard == nil {
+			continue
+		}
+		ref := wireguard.PrivateKeySecretRef
+		if ref.Namespace != envirnment.SecretsNamespace {
+			return fmt.Errorf("secret %s/%s of the private key of %s is not in namespace %s the Secrets are read from",
+				ref.Namespace, ref.Name, config.Links[i].Name, envirnment.SecretsNamespace)
+		}
+		secret := &corev1.Secret{}
+		if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+			return fmt.Errorf("failed to get the private key of %s from Secret %s/%s: %w", config.Links[i].Name, ref.Namespace, ref.Name, err)
+		}
+		key, ok := secret.Data[ref.Key]
+		if !ok {
+			return fmt.Errorf("secret %s/%s has no %s key for the private key of %s", ref.Namespace, ref.Name, ref.Key, config.Links[i].Name)
+		}
+		wireguard.PrivateKey = strings.TrimSpace(string(key))
+	}
+	return nil
+}
+
+// refersToSecret reports whether the config refers to the given Secret.
+func refersToSecret(config *models.ConfigModel, secret types.NamespacedName) bool {
+	for _, link := range config.Links {
+		if link.Wireguard != nil && link.Wireguard.PrivateKeySecretRef.Namespace == secret.Namespace &&
+			link.Wireguard.PrivateKeySecretRef.Name == secret.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// wireguardConfig returns a config with a WireGuard link whose private key is in the given Secret.
+func wireguardConfig(namespace, name string) models.ConfigModel {
+	return models.ConfigModel{Links: []models.LinkModel{{
+		Name: "wg0",
+		Type: "wireguard",
+		Wireguard: &models.WireguardModel{
+			PrivateKeySecretRef: models.SecretKeyRef{Namespace: namespace, Name: name, Key: "private-key"},
+		},
+	}}}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	secretsNamespace := envirnment.SecretsNamespace
+	envirnment.SecretsNamespace = "ipruler-operator"
+	defer func() { envirnment.SecretsNamespace = secretsNamespace }()
+
+	secret := func(namespace, name string) client.Object {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Data:       map[string][]byte{"private-key": []byte("cGxhY2Vob2xkZXI=\n")},
+		}
+	}
+	tests := []struct {
+		name    string
+		config  models.ConfigModel
+		wantKey string
+		wantErr bool
+	}{
+		{name: "Secret of the namespace", config: wireguardConfig("ipruler-operator", "wg0"), wantKey: "cGxhY2Vob2xkZXI="},
+		{name: "Secret of another namespace", config: wireguardConfig("default", "wg0"), wantErr: true},
+		{name: "missing Secret", config: wireguardConfig("ipruler-operator", "wg1"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestFullConfigReconciler(t, secret("ipruler-operator", "wg0"), secret("default", "wg0"))
+
+			err := r.resolveSecrets(context.Background(), &tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveSecrets() error = %v, want error %t", err, tt.wantErr)
+			}
+			if key := tt.config.Links[0].Wireguard.PrivateKey; key != tt.wantKey {
+				t.Errorf("private key = %q, want %q", key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestSecretReconciler(t *testing.T) {
+	ctx := context.Background()
+	fullConfig := func(name string, config models.ConfigModel) *iprulerv1.FullConfig {
+		return &iprulerv1.FullConfig{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: iprulerv1.FullConfigSpec{MergedConfig: config}}
+	}
+	c := newTestClient(t,
+		fullConfig("edge", wireguardConfig("ipruler-operator", "wg0")),
+		fullConfig("core", wireguardConfig("ipruler-operator", "wg1")),
+		fullConfig("other", wireguardConfig("default", "wg0")),
+		fullConfig("plain", models.ConfigModel{}))
+	r := &SecretReconciler{Client: c, Log: ctrl.Log.WithName("test")}
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ipruler-operator", Name: "wg0"}}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	for name, wantTriggered := range map[string]bool{"edge": true, "core": false, "other": false, "plain": false} {
+		updated := &iprulerv1.FullConfig{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, updated); err != nil {
+			t.Fatalf("failed to get FullConfig %s: %v", name, err)
+		}
+		if triggered := !lastUpdateTrigger(updated).IsZero(); triggered != wantTriggered {
+			t.Errorf("FullConfig %s triggered %t, want %t", name, triggered, wantTriggered)
+		}
+	}
+}
@@ -1,16 +1,43 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// The address families of rules and routes, named after the ip -family option
+const (
+	FamilyIPv4 = "inet"
+	FamilyIPv6 = "inet6"
+)
 
 type ConfigModel struct {
 	Rules    []RuleModel   `json:"rules,omitempty" yaml:"rules,omitempty"`
 	Settings SettingsModel `json:"settings,omitempty" yaml:"settings,omitempty"`
 	Routes   []RouteModel  `json:"routes,omitempty" yaml:"routes,omitempty"`
 	Vlans    []VlanModel   `json:"vlans,omitempty" yaml:"vlans,omitempty"`
+	// Links are created in the order of their dependencies, e.g. a VLAN after the bond it is on top of
+	Links     []LinkModel     `json:"links,omitempty" yaml:"links,omitempty"`
+	Vrfs      []VrfModel      `json:"vrfs,omitempty" yaml:"vrfs,omitempty"`
+	Neighbors []NeighborModel `json:"neighbors,omitempty" yaml:"neighbors,omitempty"`
+	// Fdb holds the static forwarding database entries of the VXLAN links
+	Fdb []FdbModel `json:"fdb,omitempty" yaml:"fdb,omitempty"`
+	// Tables name the routing tables, so that the rules and routes can refer to them by name.
+	// They are defined by the ClusterConfigs and resolved when the configs are merged.
+	Tables []TableModel `json:"tables,omitempty" yaml:"tables,omitempty"`
+	// Addresses may be templates rendered for every node, see RenderConfigModel
+	Addresses []AddressModel `json:"addresses,omitempty" yaml:"addresses,omitempty"`
 }
 
 type SettingsModel struct {
 	TableHardSync []int `json:"table-hard-sync,omitempty" yaml:"table-hard-sync,omitempty"`
+	// Sysctls are kernel parameters like net.ipv4.conf.eth2/104.rp_filter, limited to the networking ones.
+	// The agents restore the value a parameter had before they first set it once it's removed from the config
+	// or the node is cleaned up.
+	Sysctls map[string]string `json:"sysctls,omitempty" yaml:"sysctls,omitempty"`
 }
 
 type RouteModel struct {
@@ -21,11 +48,56 @@ type RouteModel struct {
 	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
 	OnLink   bool   `json:"on-link,omitempty" yaml:"on-link,omitempty"`
 	Scope    string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Metric   int    `json:"metric,omitempty" yaml:"metric,omitempty"`
+	// Src is the preferred source address of the packets sent through the route
+	Src      string `json:"src,omitempty" yaml:"src,omitempty"`
+	MTU      int    `json:"mtu,omitempty" yaml:"mtu,omitempty"`
+	AdvMSS   int    `json:"advmss,omitempty" yaml:"advmss,omitempty"`
+	InitCwnd int    `json:"initcwnd,omitempty" yaml:"initcwnd,omitempty"`
+	// Type is one of unicast (the default), blackhole, unreachable, prohibit, local and throw
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Family is inferred from the addresses of the route when empty, and defaults to inet
+	Family string `json:"family,omitempty" yaml:"family,omitempty"`
+	// Nexthops spreads a multipath route over several gateways, instead of a single Via and Dev
+	Nexthops []NexthopModel `json:"nexthops,omitempty" yaml:"nexthops,omitempty"`
+	// TableName refers to one of the named tables instead of the Table id
+	TableName string `json:"table-name,omitempty" yaml:"table-name,omitempty"`
+	// Labels are matched by the exclude selector of a NodeConfig, they are not sent to the agents
+	Labels map[string]string `json:"labels,omitempty" yaml:"-"`
+}
+
+type NexthopModel struct {
+	Via    string `json:"via,omitempty" yaml:"via,omitempty"`
+	Dev    string `json:"dev,omitempty" yaml:"dev,omitempty"`
+	Weight int    `json:"weight,omitempty" yaml:"weight,omitempty"`
+	OnLink bool   `json:"on-link,omitempty" yaml:"on-link,omitempty"`
 }
 
 type RuleModel struct {
-	From  string `json:"from,omitempty" yaml:"from,omitempty"`
-	Table int    `json:"table,omitempty" yaml:"table,omitempty"`
+	From     string `json:"from,omitempty" yaml:"from,omitempty"`
+	To       string `json:"to,omitempty" yaml:"to,omitempty"`
+	Table    int    `json:"table,omitempty" yaml:"table,omitempty"`
+	Priority int    `json:"priority,omitempty" yaml:"priority,omitempty"`
+	FwMark   string `json:"fwmark,omitempty" yaml:"fwmark,omitempty"`
+	FwMask   string `json:"fwmask,omitempty" yaml:"fwmask,omitempty"`
+	IIF      string `json:"iif,omitempty" yaml:"iif,omitempty"`
+	OIF      string `json:"oif,omitempty" yaml:"oif,omitempty"`
+	Tos      int    `json:"tos,omitempty" yaml:"tos,omitempty"`
+	IPProto  string `json:"ipproto,omitempty" yaml:"ipproto,omitempty"`
+	SPort    string `json:"sport,omitempty" yaml:"sport,omitempty"`
+	DPort    string `json:"dport,omitempty" yaml:"dport,omitempty"`
+	UIDRange string `json:"uidrange,omitempty" yaml:"uidrange,omitempty"`
+	Not      bool   `json:"not,omitempty" yaml:"not,omitempty"`
+	// Action is one of lookup (the default), blackhole, unreachable, prohibit and goto
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Goto is the priority of the rule to jump to when Action is goto
+	Goto int `json:"goto,omitempty" yaml:"goto,omitempty"`
+	// Family is inferred from the addresses of the rule when empty, and defaults to inet
+	Family string `json:"family,omitempty" yaml:"family,omitempty"`
+	// TableName refers to one of the named tables instead of the Table id
+	TableName string `json:"table-name,omitempty" yaml:"table-name,omitempty"`
+	// Labels are matched by the exclude selector of a NodeConfig, they are not sent to the agents
+	Labels map[string]string `json:"labels,omitempty" yaml:"-"`
 }
 
 type VlanModel struct {
@@ -33,10 +105,329 @@ type VlanModel struct {
 	Link     string `json:"link,omitempty" yaml:"link,omitempty"`
 	ID       int    `json:"id,omitempty" yaml:"id,omitempty"`
 	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	// Labels are matched by the exclude selector of a NodeConfig, they are not sent to the agents
+	Labels map[string]string `json:"labels,omitempty" yaml:"-"`
+}
+
+// AddressFamily returns the family of the rule, set explicitly or inferred from its from and to.
+func (rule *RuleModel) AddressFamily() string {
+	if rule.Family != "" {
+		return rule.Family
+	}
+	for _, address := range []string{rule.From, rule.To} {
+		if family := addressFamily(address); family != "" {
+			return family
+		}
+	}
+	return FamilyIPv4
+}
+
+// AddressFamily returns the family of the route, set explicitly or inferred from its addresses.
+func (route *RouteModel) AddressFamily() string {
+	if route.Family != "" {
+		return route.Family
+	}
+	addresses := []string{route.To, route.Via, route.Src}
+	for _, nexthop := range route.Nexthops {
+		addresses = append(addresses, nexthop.Via)
+	}
+	for _, address := range addresses {
+		if family := addressFamily(address); family != "" {
+			return family
+		}
+	}
+	return FamilyIPv4
+}
+
+// addressFamily returns the family of an IP address or a CIDR, or an empty string for anything else like "default".
+func addressFamily(value string) string {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		var err error
+		if ip, _, err = net.ParseCIDR(value); err != nil {
+			return ""
+		}
+	}
+	if ip.To4() != nil {
+		return FamilyIPv4
+	}
+	return FamilyIPv6
 }
 
-func (in *ConfigModel) DeepCopyInto(out *ConfigModel) {
-	*out = *in
+// destination returns the to of the route with 0.0.0.0/0 and ::/0 spelled as default.
+func (route *RouteModel) destination() string {
+	if _, network, err := net.ParseCIDR(route.To); err == nil {
+		if ones, _ := network.Mask.Size(); ones == 0 {
+			return "default"
+		}
+	}
+	return route.To
+}
+
+// TableModel names a routing table, like an entry of rt_tables.
+type TableModel struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	ID   int    `json:"id,omitempty" yaml:"id,omitempty"`
+	// HardSync adds the table to the table-hard-sync settings
+	HardSync bool `json:"hard-sync,omitempty" yaml:"hard-sync,omitempty"`
+}
+
+// LinkModel is an interface of the given type, configured by the sub-struct named after the type.
+type LinkModel struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Type is one of bond, bridge, dummy, macvlan, ipvlan, vlan, vxlan, gre, ipip and wireguard
+	Type      string          `json:"type,omitempty" yaml:"type,omitempty"`
+	MTU       int             `json:"mtu,omitempty" yaml:"mtu,omitempty"`
+	Bond      *BondModel      `json:"bond,omitempty" yaml:"bond,omitempty"`
+	Bridge    *BridgeModel    `json:"bridge,omitempty" yaml:"bridge,omitempty"`
+	Macvlan   *MacvlanModel   `json:"macvlan,omitempty" yaml:"macvlan,omitempty"`
+	Ipvlan    *IpvlanModel    `json:"ipvlan,omitempty" yaml:"ipvlan,omitempty"`
+	Vlan      *VlanModel      `json:"vlan,omitempty" yaml:"vlan,omitempty"`
+	Vxlan     *VxlanModel     `json:"vxlan,omitempty" yaml:"vxlan,omitempty"`
+	Gre       *TunnelModel    `json:"gre,omitempty" yaml:"gre,omitempty"`
+	Ipip      *TunnelModel    `json:"ipip,omitempty" yaml:"ipip,omitempty"`
+	Wireguard *WireguardModel `json:"wireguard,omitempty" yaml:"wireguard,omitempty"`
+}
+
+type BondModel struct {
+	Mode   string   `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Miimon int      `json:"miimon,omitempty" yaml:"miimon,omitempty"`
+	Slaves []string `json:"slaves,omitempty" yaml:"slaves,omitempty"`
+}
+
+type BridgeModel struct {
+	Ports         []string `json:"ports,omitempty" yaml:"ports,omitempty"`
+	STP           bool     `json:"stp,omitempty" yaml:"stp,omitempty"`
+	VlanFiltering bool     `json:"vlan-filtering,omitempty" yaml:"vlan-filtering,omitempty"`
+}
+
+type MacvlanModel struct {
+	Link string `json:"link,omitempty" yaml:"link,omitempty"`
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+type IpvlanModel struct {
+	Link string `json:"link,omitempty" yaml:"link,omitempty"`
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+type VxlanModel struct {
+	VNI    int    `json:"vni,omitempty" yaml:"vni,omitempty"`
+	Local  string `json:"local,omitempty" yaml:"local,omitempty"`
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+	// Group is the multicast group to join instead of a single Remote
+	Group   string `json:"group,omitempty" yaml:"group,omitempty"`
+	DstPort int    `json:"dstport,omitempty" yaml:"dstport,omitempty"`
+	// Dev is the underlay interface of the tunnel
+	Dev string `json:"dev,omitempty" yaml:"dev,omitempty"`
+}
+
+// TunnelModel is a GRE or an IPIP tunnel.
+type TunnelModel struct {
+	Local  string `json:"local,omitempty" yaml:"local,omitempty"`
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+	TTL    int    `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Dev is the underlay interface of the tunnel
+	Dev string `json:"dev,omitempty" yaml:"dev,omitempty"`
+}
+
+type WireguardModel struct {
+	PrivateKeySecretRef SecretKeyRef `json:"private-key-secret-ref,omitempty" yaml:"private-key-secret-ref,omitempty"`
+	// PrivateKey is resolved from PrivateKeySecretRef right before the config is sent to the agents,
+	// so it's never stored in the resources
+	PrivateKey string               `json:"-" yaml:"private-key,omitempty"`
+	ListenPort int                  `json:"listen-port,omitempty" yaml:"listen-port,omitempty"`
+	Peers      []WireguardPeerModel `json:"peers,omitempty" yaml:"peers,omitempty"`
+}
+
+type WireguardPeerModel struct {
+	PublicKey string `json:"public-key,omitempty" yaml:"public-key,omitempty"`
+	// Endpoint is the host:port of the peer
+	Endpoint            string   `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	AllowedIPs          []string `json:"allowed-ips,omitempty" yaml:"allowed-ips,omitempty"`
+	PersistentKeepalive int      `json:"persistent-keepalive,omitempty" yaml:"persistent-keepalive,omitempty"`
+}
+
+// SecretKeyRef refers to a key of a Secret.
+type SecretKeyRef struct {
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	Key       string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// VrfModel is a VRF device bound to a routing table, with the interfaces enslaved into it.
+type VrfModel struct {
+	Name       string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Table      int      `json:"table,omitempty" yaml:"table,omitempty"`
+	Interfaces []string `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
+}
+
+// NeighborModel is an ARP or NDP entry.
+type NeighborModel struct {
+	Dev    string `json:"dev,omitempty" yaml:"dev,omitempty"`
+	IP     string `json:"ip,omitempty" yaml:"ip,omitempty"`
+	LLAddr string `json:"lladdr,omitempty" yaml:"lladdr,omitempty"`
+	// State is one of permanent (the default), noarp, reachable and stale
+	State string `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+// FdbModel forwards the frames for LLAddr, or every broadcast and unknown frame for 00:00:00:00:00:00,
+// to the VTEP at Dst.
+type FdbModel struct {
+	Dev    string `json:"dev,omitempty" yaml:"dev,omitempty"`
+	LLAddr string `json:"lladdr,omitempty" yaml:"lladdr,omitempty"`
+	Dst    string `json:"dst,omitempty" yaml:"dst,omitempty"`
+	VNI    int    `json:"vni,omitempty" yaml:"vni,omitempty"`
+}
+
+type AddressModel struct {
+	Dev string `json:"dev,omitempty" yaml:"dev,omitempty"`
+	// Address is the address of the interface along with its prefix length, e.g. 172.31.201.11/24
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	Scope   string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Label   string `json:"label,omitempty" yaml:"label,omitempty"`
+	// Peer is the address of the remote end of a point-to-point interface
+	Peer          string `json:"peer,omitempty" yaml:"peer,omitempty"`
+	NoPrefixRoute bool   `json:"noprefixroute,omitempty" yaml:"noprefixroute,omitempty"`
+}
+
+// key identifies the rule when configs are merged or compared
+func (rule *RuleModel) key() string {
+	return fmt.Sprintf("%s-%s-%s-%d-%s-%d-%s-%s-%s-%s-%d-%s-%s-%s-%s-%t-%s-%d", rule.AddressFamily(), rule.From, rule.To, rule.Table, rule.TableName,
+		rule.Priority, rule.FwMark, rule.FwMask, rule.IIF, rule.OIF, rule.Tos, rule.IPProto, rule.SPort, rule.DPort, rule.UIDRange, rule.Not, rule.Action, rule.Goto)
+}
+
+// key identifies the route when configs are merged or compared
+func (route *RouteModel) key() string {
+	// the order of the nexthops doesn't matter to the kernel
+	nexthops := make([]string, 0, len(route.Nexthops))
+	for _, nexthop := range route.Nexthops {
+		nexthops = append(nexthops, fmt.Sprintf("%s/%s/%d/%t", nexthop.Via, nexthop.Dev, nexthop.Weight, nexthop.OnLink))
+	}
+	sort.Strings(nexthops)
+	return fmt.Sprintf("%s-%s-%s-%d-%s-%s-%s-%t-%s-%d-%s-%d-%d-%d-%s-[%s]", route.AddressFamily(), route.destination(), route.Via, route.Table, route.TableName, route.Dev, route.Protocol, route.OnLink, route.Scope,
+		route.Metric, route.Src, route.MTU, route.AdvMSS, route.InitCwnd, route.Type, strings.Join(nexthops, ","))
+}
+
+// key identifies the VLAN when configs are merged or compared
+func (vlan *VlanModel) key() string {
+	return fmt.Sprintf("%s-%s-%d-%s", vlan.Name, vlan.Link, vlan.ID, vlan.Protocol)
+}
+
+// key identifies the link when configs are merged or compared
+func (link *LinkModel) key() string {
+	wireguard := link.Wireguard
+	if wireguard != nil {
+		// the private key is only there once resolved, the secret it is resolved from identifies it
+		resolved := *wireguard
+		resolved.PrivateKey = ""
+		wireguard = &resolved
+	}
+	return fmt.Sprintf("%s-%s-%d-%v-%v-%v-%v-%v-%v-%v-%v-%v", link.Name, link.Type, link.MTU, link.Bond, link.Bridge, link.Macvlan, link.Ipvlan, link.Vlan,
+		link.Vxlan, link.Gre, link.Ipip, wireguard)
+}
+
+// key identifies the VRF when configs are merged or compared
+func (vrf *VrfModel) key() string {
+	return fmt.Sprintf("%s-%d-%s", vrf.Name, vrf.Table, strings.Join(vrf.Interfaces, ","))
+}
+
+// key identifies the neighbor when configs are merged or compared
+func (neighbor *NeighborModel) key() string {
+	return fmt.Sprintf("%s-%s-%s-%s", neighbor.Dev, neighbor.IP, neighbor.LLAddr, neighbor.State)
+}
+
+// key identifies the FDB entry when configs are merged or compared
+func (fdb *FdbModel) key() string {
+	return fmt.Sprintf("%s-%s-%s-%d", fdb.Dev, fdb.LLAddr, fdb.Dst, fdb.VNI)
+}
+
+// key identifies the table when configs are merged
+func (table *TableModel) key() string {
+	return fmt.Sprintf("%s-%d-%t", table.Name, table.ID, table.HardSync)
+}
+
+// key identifies the address when configs are merged or compared
+func (address *AddressModel) key() string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s-%t", address.Dev, address.Address, address.Scope, address.Label, address.Peer, address.NoPrefixRoute)
+}
+
+// identity identifies the rule a later config overrides when configs are merged. Rules only differing in their
+// action are the same rule, the ones matching other packets are not.
+func (rule *RuleModel) identity() string {
+	identity := "rule"
+	if rule.Not {
+		identity += " not"
+	}
+	from := rule.From
+	if from == "" {
+		from = "all"
+	}
+	identity += " from " + from
+	for _, selector := range []struct{ name, value string }{
+		{"to", rule.To}, {"fwmark", rule.FwMark}, {"fwmask", rule.FwMask}, {"iif", rule.IIF}, {"oif", rule.OIF},
+		{"ipproto", rule.IPProto}, {"sport", rule.SPort}, {"dport", rule.DPort}, {"uidrange", rule.UIDRange},
+	} {
+		if selector.value != "" {
+			identity += fmt.Sprintf(" %s %s", selector.name, selector.value)
+		}
+	}
+	if rule.Tos != 0 {
+		identity += fmt.Sprintf(" tos %d", rule.Tos)
+	}
+	return fmt.Sprintf("%s table %s priority %d%s", identity, tableIdentity(rule.Table, rule.TableName), rule.Priority,
+		familyIdentity(rule.AddressFamily()))
+}
+
+// identity identifies the route a later config overrides when configs are merged
+func (route *RouteModel) identity() string {
+	table := route.Table
+	if table == 0 && route.TableName == "" {
+		table = tableMain
+	}
+	return fmt.Sprintf("route to %s table %s%s", route.destination(), tableIdentity(table, route.TableName), familyIdentity(route.AddressFamily()))
+}
+
+// identity identifies the VLAN a later config overrides when configs are merged
+func (vlan *VlanModel) identity() string {
+	return "vlan " + vlan.Name
+}
+
+// identity identifies the link a later config overrides when configs are merged
+func (link *LinkModel) identity() string {
+	return "link " + link.Name
+}
+
+// identity identifies the VRF a later config overrides when configs are merged
+func (vrf *VrfModel) identity() string {
+	return "vrf " + vrf.Name
+}
+
+// identity identifies the neighbor a later config overrides when configs are merged
+func (neighbor *NeighborModel) identity() string {
+	return fmt.Sprintf("neighbor %s dev %s", neighbor.IP, neighbor.Dev)
+}
+
+// identity identifies the address a later config overrides when configs are merged
+func (address *AddressModel) identity() string {
+	return fmt.Sprintf("address %s dev %s", address.Address, address.Dev)
+}
+
+// tableIdentity names the table of an entry whose table name is resolved with resolveTableNames,
+// a name is left only when the table is unknown
+func tableIdentity(id int, name string) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprint(id)
+}
+
+// familyIdentity tells IPv6 entries apart from the IPv4 ones sharing the same addresses, e.g. "all" or "default"
+func familyIdentity(family string) string {
+	if family == FamilyIPv6 {
+		return " family " + FamilyIPv6
+	}
+	return ""
 }
 
 // Merge c2 into C1
@@ -44,68 +435,104 @@ func (c1 *ConfigModel) Merge(c2 *ConfigModel) {
 
 }
 
-func MergeConfigModels(c1 *ConfigModel, c2 *ConfigModel) ConfigModel {
+// MergeConfigModels merges c2 on top of c1. Identical entries are kept once, and an entry of c2 overrides the entries
+// of c1 with the same identity, e.g. a route to the same destination in the same table. The rules and routes of the
+// merged config refer to the tables of c1 and c2 by id. The identities of the overridden entries are returned along
+// with the merged config.
+func MergeConfigModels(c1 *ConfigModel, c2 *ConfigModel) (ConfigModel, []string) {
 	var mergedConfig ConfigModel
+	var overrides []string
 
-	ruleMap := make(map[string]bool)
-	routeMap := make(map[string]bool)
-	vlanMap := make(map[string]bool)
-	tableHardSyncMap := make(map[int]bool)
+	// the identities of the entries don't depend on whether they refer to their table by name or by id
+	tables := TableIDs(c1, c2)
+	resolved1, resolved2 := *c1, *c2
+	resolveTableNames(&resolved1, tables)
+	resolveTableNames(&resolved2, tables)
+	c1, c2 = &resolved1, &resolved2
 
-	// Helper function to add unique rules
-	addRules := func(rules []RuleModel) {
-		for _, rule := range rules {
-			key := fmt.Sprintf("%s-%d", rule.From, rule.Table)
-			if !ruleMap[key] {
-				mergedConfig.Rules = append(mergedConfig.Rules, rule)
-				ruleMap[key] = true
-			}
+	mergedConfig.Rules = mergeEntries(c1.Rules, c2.Rules, (*RuleModel).key, (*RuleModel).identity, &overrides)
+	mergedConfig.Routes = mergeEntries(c1.Routes, c2.Routes, (*RouteModel).key, (*RouteModel).identity, &overrides)
+	mergedConfig.Vlans = mergeEntries(c1.Vlans, c2.Vlans, (*VlanModel).key, (*VlanModel).identity, &overrides)
+	mergedConfig.Links = mergeEntries(c1.Links, c2.Links, (*LinkModel).key, (*LinkModel).identity, &overrides)
+	mergedConfig.Vrfs = mergeEntries(c1.Vrfs, c2.Vrfs, (*VrfModel).key, (*VrfModel).identity, &overrides)
+	mergedConfig.Neighbors = mergeEntries(c1.Neighbors, c2.Neighbors, (*NeighborModel).key, (*NeighborModel).identity, &overrides)
+	mergedConfig.Addresses = mergeEntries(c1.Addresses, c2.Addresses, (*AddressModel).key, (*AddressModel).identity, &overrides)
+	// FDB entries have no identity besides their content, and conflicting tables are rejected by the validation
+	mergedConfig.Fdb = mergeEntries(c1.Fdb, c2.Fdb, (*FdbModel).key, nil, &overrides)
+	mergedConfig.Tables = mergeEntries(c1.Tables, c2.Tables, (*TableModel).key, nil, &overrides)
+
+	tableHardSyncMap := make(map[int]bool)
+	for _, sync := range append(slices.Clone(c1.Settings.TableHardSync), c2.Settings.TableHardSync...) {
+		if !tableHardSyncMap[sync] {
+			mergedConfig.Settings.TableHardSync = append(mergedConfig.Settings.TableHardSync, sync)
+			tableHardSyncMap[sync] = true
 		}
 	}
 
-	// Helper function to add unique table hard sync settings
-	addTableHardSync := func(syncs []int) {
-		for _, sync := range syncs {
-			if !tableHardSyncMap[sync] {
-				mergedConfig.Settings.TableHardSync = append(mergedConfig.Settings.TableHardSync, sync)
-				tableHardSyncMap[sync] = true
-			}
+	// the sysctls of c2 override the ones of c1
+	for key, value := range c1.Settings.Sysctls {
+		addSysctl(&mergedConfig, key, value)
+	}
+	sysctls := make([]string, 0, len(c2.Settings.Sysctls))
+	for key := range c2.Settings.Sysctls {
+		sysctls = append(sysctls, key)
+	}
+	sort.Strings(sysctls)
+	for _, key := range sysctls {
+		if value, ok := mergedConfig.Settings.Sysctls[key]; ok && value != c2.Settings.Sysctls[key] {
+			overrides = append(overrides, "sysctl "+key)
 		}
+		addSysctl(&mergedConfig, key, c2.Settings.Sysctls[key])
 	}
 
-	// Helper function to add unique routes
-	addRoutes := func(routes []RouteModel) {
-		for _, route := range routes {
-			key := fmt.Sprintf("%s-%s-%d-%s-%s-%t-%s", route.To, route.Via, route.Table, route.Dev, route.Protocol, route.OnLink, route.Scope)
-			if !routeMap[key] {
-				mergedConfig.Routes = append(mergedConfig.Routes, route)
-				routeMap[key] = true
-			}
+	// the links of c2 may be the dependencies of the links of c1, a cycle is left for the validation to reject
+	mergedConfig.Links, _ = orderLinks(mergedConfig.Links)
+
+	return mergedConfig, overrides
+}
+
+// mergeEntries merges the entries of a section of c2 on top of the ones of c1, keeping identical entries once.
+// When identity is set, the entries of c1 sharing their identity with a different entry of c2 are left out, and their
+// identity is added to the overrides.
+func mergeEntries[T any](entries1 []T, entries2 []T, key func(*T) string, identity func(*T) string, overrides *[]string) []T {
+	keys2 := make(map[string]bool)
+	identities2 := make(map[string]bool)
+	for i := range entries2 {
+		keys2[key(&entries2[i])] = true
+		if identity != nil {
+			identities2[identity(&entries2[i])] = true
 		}
 	}
 
-	// Helper function to add unique VLANs
-	addVlans := func(vlans []VlanModel) {
-		for _, vlan := range vlans {
-			key := fmt.Sprintf("%s-%s-%d-%s", vlan.Name, vlan.Link, vlan.ID, vlan.Protocol)
-			if !vlanMap[key] {
-				mergedConfig.Vlans = append(mergedConfig.Vlans, vlan)
-				vlanMap[key] = true
+	var merged []T
+	seen := make(map[string]bool)
+	overridden := make(map[string]bool)
+	add := func(entry *T) {
+		if k := key(entry); !seen[k] {
+			merged = append(merged, *entry)
+			seen[k] = true
+		}
+	}
+	for i := range entries1 {
+		entry := &entries1[i]
+		if identity != nil && identities2[identity(entry)] && !keys2[key(entry)] {
+			if id := identity(entry); !overridden[id] {
+				*overrides = append(*overrides, id)
+				overridden[id] = true
 			}
+			continue
 		}
+		add(entry)
 	}
+	for i := range entries2 {
+		add(&entries2[i])
+	}
+	return merged
+}
 
-	// Add unique elements from c1
-	addRules(c1.Rules)
-	addTableHardSync(c1.Settings.TableHardSync)
-	addRoutes(c1.Routes)
-	addVlans(c1.Vlans)
-
-	// Add unique elements from c2
-	addRules(c2.Rules)
-	addTableHardSync(c2.Settings.TableHardSync)
-	addRoutes(c2.Routes)
-	addVlans(c2.Vlans)
-
-	return mergedConfig
+func addSysctl(config *ConfigModel, key string, value string) {
+	if config.Settings.Sysctls == nil {
+		config.Settings.Sysctls = map[string]string{}
+	}
+	config.Settings.Sysctls[key] = value
 }
@@ -0,0 +1,297 @@
+package models
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeConfigModels", func() {
+	It("should keep rules of the same config that only differ in their selectors", func() {
+		c1 := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", Table: 102},
+				{From: "172.31.201.11/32", Table: 102, FwMark: "0x10"},
+			},
+		}
+		c2 := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", Table: 102, Priority: 100},
+				{From: "172.31.201.11/32", Table: 102, Priority: 100, DPort: "443", IPProto: "tcp"},
+			},
+		}
+		merged, overrides := MergeConfigModels(c1, c2)
+		Expect(overrides).To(BeEmpty())
+		Expect(merged.Rules).To(Equal([]RuleModel{
+			{From: "172.31.201.11/32", Table: 102},
+			{From: "172.31.201.11/32", Table: 102, FwMark: "0x10"},
+			{From: "172.31.201.11/32", Table: 102, Priority: 100},
+			{From: "172.31.201.11/32", Table: 102, Priority: 100, DPort: "443", IPProto: "tcp"},
+		}))
+	})
+
+	It("should override the entries of c1 with the same identity", func() {
+		c1 := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", Table: 102, Priority: 100},
+				{FwMark: "0x1", Table: 100},
+				{From: "172.31.201.12/32", Priority: 50, Action: "prohibit"},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Table: 102},
+				{To: "10.0.0.0/8", Via: "172.31.201.1"},
+			},
+			Vlans: []VlanModel{
+				{Name: "eth2.104", Link: "eth2", ID: 104},
+			},
+			Settings: SettingsModel{Sysctls: map[string]string{"net.ipv4.ip_forward": "1", "net.ipv4.conf.all.rp_filter": "2"}},
+		}
+		c2 := &ConfigModel{
+			Rules: []RuleModel{
+				{IIF: "eth2", Table: 100},
+				{From: "172.31.201.11/32", Table: 102, Priority: 100, IIF: "eth2"},
+				{From: "172.31.201.12/32", Priority: 50, Action: "unreachable"},
+			},
+			Routes: []RouteModel{
+				{To: "0.0.0.0/0", Via: "172.31.201.2", Table: 102},
+				{To: "10.0.0.0/8", Via: "172.31.201.2", TableName: "main"},
+			},
+			Vlans: []VlanModel{
+				{Name: "eth2.104", Link: "eth3", ID: 104},
+			},
+			Settings: SettingsModel{Sysctls: map[string]string{"net.ipv4.ip_forward": "1", "net.ipv4.conf.all.rp_filter": "1"}},
+		}
+		merged, overrides := MergeConfigModels(c1, c2)
+		// rules matching other packets coexist, whatever their table and priority
+		Expect(merged.Rules).To(Equal([]RuleModel{c1.Rules[0], c1.Rules[1], c2.Rules[0], c2.Rules[1], c2.Rules[2]}))
+		Expect(merged.Routes).To(Equal([]RouteModel{
+			{To: "0.0.0.0/0", Via: "172.31.201.2", Table: 102},
+			{To: "10.0.0.0/8", Via: "172.31.201.2", Table: tableMain},
+		}))
+		Expect(merged.Vlans).To(Equal(c2.Vlans))
+		Expect(merged.Settings.Sysctls).To(Equal(c2.Settings.Sysctls))
+		Expect(overrides).To(Equal([]string{
+			"rule from 172.31.201.12/32 table 0 priority 50",
+			"route to default table 102",
+			"route to 10.0.0.0/8 table 254",
+			"vlan eth2.104",
+			"sysctl net.ipv4.conf.all.rp_filter",
+		}))
+	})
+
+	It("should override the entries of c1 referring to the same table by name or by id", func() {
+		c1 := &ConfigModel{
+			Tables: []TableModel{{Name: "isp", ID: 102}},
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", TableName: "isp", Priority: 100, Action: "lookup"},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", TableName: "isp"},
+			},
+		}
+		c2 := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", Table: 102, Priority: 100},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.2", Table: 102},
+				{To: "10.0.0.0/8", Via: "172.31.201.2", TableName: "isp2"},
+			},
+		}
+		merged, overrides := MergeConfigModels(c1, c2)
+		Expect(merged.Rules).To(Equal(c2.Rules))
+		// the table of isp2 is unknown, it is left for ResolveTableNames to report
+		Expect(merged.Routes).To(Equal(c2.Routes))
+		Expect(overrides).To(Equal([]string{
+			"rule from 172.31.201.11/32 table 102 priority 100",
+			"route to default table 102",
+		}))
+		Expect(c1.Routes[0].TableName).To(Equal("isp"))
+	})
+
+	It("should keep routes that only differ in their attributes", func() {
+		c1 := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Table: 102, Src: "172.31.201.11"},
+			},
+		}
+		c2 := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Table: 102, Src: "172.31.201.11"},
+				{To: "default", Via: "172.31.201.1", Table: 102, Src: "172.31.201.12", Metric: 200},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Routes).To(Equal([]RouteModel{
+			{To: "default", Via: "172.31.201.1", Table: 102, Src: "172.31.201.11"},
+			{To: "default", Via: "172.31.201.1", Table: 102, Src: "172.31.201.12", Metric: 200},
+		}))
+	})
+
+	It("should merge multipath routes regardless of the order of their nexthops", func() {
+		c1 := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Table: 102, Nexthops: []NexthopModel{
+					{Via: "172.31.201.1", Weight: 1},
+					{Via: "172.31.202.1", Weight: 3},
+				}},
+			},
+		}
+		c2 := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Table: 102, Nexthops: []NexthopModel{
+					{Via: "172.31.202.1", Weight: 3},
+					{Via: "172.31.201.1", Weight: 1},
+				}},
+				{To: "default", Table: 102, Nexthops: []NexthopModel{
+					{Via: "172.31.202.1", Weight: 1},
+					{Via: "172.31.201.1", Weight: 1},
+				}},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Routes).To(Equal([]RouteModel{c1.Routes[0], c2.Routes[1]}))
+	})
+
+	It("should infer the address family of rules and routes", func() {
+		Expect((&RuleModel{From: "2001:db8:201::11/128"}).AddressFamily()).To(Equal(FamilyIPv6))
+		Expect((&RuleModel{From: "all", To: "172.31.201.0/24"}).AddressFamily()).To(Equal(FamilyIPv4))
+		Expect((&RuleModel{FwMark: "0x10"}).AddressFamily()).To(Equal(FamilyIPv4))
+		Expect((&RuleModel{FwMark: "0x10", Family: FamilyIPv6}).AddressFamily()).To(Equal(FamilyIPv6))
+		Expect((&RouteModel{To: "default", Via: "fe80::1"}).AddressFamily()).To(Equal(FamilyIPv6))
+		Expect((&RouteModel{To: "default", Nexthops: []NexthopModel{{Via: "2001:db8:201::1"}}}).AddressFamily()).To(Equal(FamilyIPv6))
+		Expect((&RouteModel{To: "default", Dev: "eth2.104"}).AddressFamily()).To(Equal(FamilyIPv4))
+	})
+
+	It("should keep the IPv4 and IPv6 entries of a mixed-family merge apart", func() {
+		c1 := &ConfigModel{
+			Rules: []RuleModel{
+				{FwMark: "0x10", Table: 102},
+				{From: "172.31.201.11/32", Table: 102},
+			},
+			Routes: []RouteModel{
+				{To: "default", Dev: "eth2.104", Table: 102},
+				{To: "default", Via: "172.31.201.1", Table: 102},
+			},
+		}
+		c2 := &ConfigModel{
+			Rules: []RuleModel{
+				{FwMark: "0x10", Table: 102, Family: FamilyIPv6},
+				{From: "2001:db8:201::11/128", Table: 102},
+				{From: "172.31.201.11/32", Table: 102, Family: FamilyIPv4},
+			},
+			Routes: []RouteModel{
+				{To: "default", Dev: "eth2.104", Table: 102, Family: FamilyIPv6},
+				{To: "::/0", Via: "2001:db8:201::1", Table: 102},
+				{To: "0.0.0.0/0", Via: "172.31.201.1", Table: 102},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Rules).To(Equal([]RuleModel{
+			{FwMark: "0x10", Table: 102},
+			{From: "172.31.201.11/32", Table: 102},
+			{FwMark: "0x10", Table: 102, Family: FamilyIPv6},
+			{From: "2001:db8:201::11/128", Table: 102},
+		}))
+		// the IPv4 default route of c2 overrides the one through eth2.104, but not the IPv6 one
+		Expect(merged.Routes).To(Equal([]RouteModel{
+			{To: "default", Via: "172.31.201.1", Table: 102},
+			{To: "default", Dev: "eth2.104", Table: 102, Family: FamilyIPv6},
+			{To: "::/0", Via: "2001:db8:201::1", Table: 102},
+		}))
+	})
+
+	It("should merge the addresses", func() {
+		c1 := &ConfigModel{
+			Addresses: []AddressModel{
+				{Dev: "lo", Address: "10.255.0.1/32"},
+			},
+		}
+		c2 := &ConfigModel{
+			Addresses: []AddressModel{
+				{Dev: "lo", Address: "10.255.0.1/32"},
+				{Dev: "eth2.104", Address: `{{ index .Annotations "ipruler.pegah.tech/eth2.104" }}/24`},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Addresses).To(Equal([]AddressModel{c1.Addresses[0], c2.Addresses[1]}))
+	})
+
+	It("should order the merged links by their dependencies", func() {
+		c1 := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "bond0.104", Type: "vlan", Vlan: &VlanModel{Link: "bond0", ID: 104}},
+			},
+		}
+		c2 := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "bond0", Type: "bond", Bond: &BondModel{Mode: "active-backup", Slaves: []string{"eth0", "eth1"}}},
+				{Name: "bond0.104", Type: "vlan", Vlan: &VlanModel{Link: "bond0", ID: 104}},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Links).To(Equal([]LinkModel{c2.Links[0], c1.Links[0]}))
+	})
+
+	It("should merge the VRFs", func() {
+		c1 := &ConfigModel{
+			Vrfs: []VrfModel{{Name: "storage", Table: 110, Interfaces: []string{"eth2.110"}}},
+		}
+		c2 := &ConfigModel{
+			Vrfs: []VrfModel{
+				{Name: "storage", Table: 110, Interfaces: []string{"eth2.110"}},
+				{Name: "backup", Table: 111, Interfaces: []string{"eth2.111"}},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Vrfs).To(Equal([]VrfModel{c1.Vrfs[0], c2.Vrfs[1]}))
+	})
+
+	It("should keep the private keys of WireGuard links out of the resources", func() {
+		link := LinkModel{Name: "wg0", Type: "wireguard", Wireguard: &WireguardModel{
+			PrivateKeySecretRef: SecretKeyRef{Namespace: "kube-system", Name: "wg0", Key: "private-key"},
+			PrivateKey:          "resolved",
+		}}
+		data, err := json.Marshal(link)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("resolved"))
+
+		// a resolved link is the same link as far as merging and drift are concerned
+		unresolved := *link.DeepCopy()
+		unresolved.Wireguard.PrivateKey = ""
+		Expect(link.key()).To(Equal(unresolved.key()))
+	})
+
+	It("should merge the neighbors and the FDB entries", func() {
+		c1 := &ConfigModel{
+			Neighbors: []NeighborModel{{Dev: "eth2.104", IP: "172.31.201.50", LLAddr: "52:54:00:12:34:56"}},
+			Fdb:       []FdbModel{{Dev: "vxlan100", LLAddr: "00:00:00:00:00:00", Dst: "172.31.202.11"}},
+		}
+		c2 := &ConfigModel{
+			Neighbors: []NeighborModel{
+				{Dev: "eth2.104", IP: "172.31.201.50", LLAddr: "52:54:00:12:34:56"},
+				{Dev: "eth2.104", IP: "172.31.201.51", LLAddr: "52:54:00:12:34:57"},
+			},
+			Fdb: []FdbModel{
+				{Dev: "vxlan100", LLAddr: "00:00:00:00:00:00", Dst: "172.31.202.11"},
+				{Dev: "vxlan100", LLAddr: "00:00:00:00:00:00", Dst: "172.31.202.12"},
+			},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Neighbors).To(Equal([]NeighborModel{c1.Neighbors[0], c2.Neighbors[1]}))
+		Expect(merged.Fdb).To(Equal([]FdbModel{c1.Fdb[0], c2.Fdb[1]}))
+	})
+
+	It("should let the sysctls of c2 override the ones of c1", func() {
+		c1 := &ConfigModel{
+			Settings: SettingsModel{Sysctls: map[string]string{"net.ipv4.ip_forward": "1", "net.ipv4.conf.all.rp_filter": "1"}},
+		}
+		c2 := &ConfigModel{
+			Settings: SettingsModel{Sysctls: map[string]string{"net.ipv4.conf.all.rp_filter": "2"}},
+		}
+		merged, _ := MergeConfigModels(c1, c2)
+		Expect(merged.Settings.Sysctls).To(Equal(map[string]string{"net.ipv4.ip_forward": "1", "net.ipv4.conf.all.rp_filter": "2"}))
+		Expect(c1.Settings.Sysctls).To(HaveKeyWithValue("net.ipv4.conf.all.rp_filter", "1"))
+	})
+})
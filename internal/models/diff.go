@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigDiff is the difference between the config a node is supposed to have and the config it actually has.
+// +kubebuilder:object:generate=false
+type ConfigDiff struct {
+	// Missing holds what is desired but not applied on the node
+	Missing ConfigModel
+	// Unexpected holds what is applied on the node but not desired
+	Unexpected ConfigModel
+}
+
+// DiffConfigModels compares the desired config with the actual one regardless of the order of their elements.
+func DiffConfigModels(desired *ConfigModel, actual *ConfigModel) ConfigDiff {
+	return ConfigDiff{
+		Missing:    subtractConfigModel(desired, actual),
+		Unexpected: subtractConfigModel(actual, desired),
+	}
+}
+
+// subtractConfigModel returns the elements of c1 that are not in c2.
+func subtractConfigModel(c1 *ConfigModel, c2 *ConfigModel) ConfigModel {
+	var result ConfigModel
+
+	result.Rules = subtractEntries(c1.Rules, c2.Rules, (*RuleModel).key)
+	result.Routes = subtractEntries(c1.Routes, c2.Routes, (*RouteModel).key)
+	result.Vlans = subtractEntries(c1.Vlans, c2.Vlans, (*VlanModel).key)
+	result.Links = subtractEntries(c1.Links, c2.Links, (*LinkModel).key)
+	result.Vrfs = subtractEntries(c1.Vrfs, c2.Vrfs, (*VrfModel).key)
+	result.Neighbors = subtractEntries(c1.Neighbors, c2.Neighbors, (*NeighborModel).key)
+	result.Fdb = subtractEntries(c1.Fdb, c2.Fdb, (*FdbModel).key)
+	result.Addresses = subtractEntries(c1.Addresses, c2.Addresses, (*AddressModel).key)
+
+	tables := make(map[int]bool)
+	for _, table := range c2.Settings.TableHardSync {
+		tables[table] = true
+	}
+	for _, table := range c1.Settings.TableHardSync {
+		if !tables[table] {
+			result.Settings.TableHardSync = append(result.Settings.TableHardSync, table)
+		}
+	}
+
+	// a sysctl set to another value is both missing and unexpected
+	for key, value := range c1.Settings.Sysctls {
+		if other, ok := c2.Settings.Sysctls[key]; !ok || other != value {
+			addSysctl(&result, key, value)
+		}
+	}
+
+	return result
+}
+
+// subtractEntries returns the entries of a section of c1 whose key is not the key of any entry of c2.
+func subtractEntries[T any](entries1 []T, entries2 []T, key func(*T) string) []T {
+	keys2 := make(map[string]bool)
+	for i := range entries2 {
+		keys2[key(&entries2[i])] = true
+	}
+
+	var result []T
+	for i := range entries1 {
+		if !keys2[key(&entries1[i])] {
+			result = append(result, entries1[i])
+		}
+	}
+	return result
+}
+
+// Empty reports whether both configs are the same.
+func (d *ConfigDiff) Empty() bool {
+	return isEmptyConfigModel(&d.Missing) && isEmptyConfigModel(&d.Unexpected)
+}
+
+// String describes the difference in a single line, e.g. "missing rule from 10.0.0.1/32 table 100; unexpected vlan eth2.104".
+func (d *ConfigDiff) String() string {
+	var parts []string
+	if missing := describeConfigModel(&d.Missing); missing != "" {
+		parts = append(parts, "missing "+missing)
+	}
+	if unexpected := describeConfigModel(&d.Unexpected); unexpected != "" {
+		parts = append(parts, "unexpected "+unexpected)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func isEmptyConfigModel(config *ConfigModel) bool {
+	return len(config.Rules) == 0 && len(config.Routes) == 0 && len(config.Vlans) == 0 && len(config.Links) == 0 &&
+		len(config.Vrfs) == 0 && len(config.Neighbors) == 0 && len(config.Fdb) == 0 && len(config.Addresses) == 0 &&
+		len(config.Settings.TableHardSync) == 0 && len(config.Settings.Sysctls) == 0
+}
+
+func describeConfigModel(config *ConfigModel) string {
+	var elements []string
+	for _, rule := range config.Rules {
+		elements = append(elements, fmt.Sprintf("rule from %s table %d", rule.From, rule.Table))
+	}
+	for _, route := range config.Routes {
+		description := "route to " + route.To
+		if route.Via != "" {
+			description += " via " + route.Via
+		}
+		if route.Dev != "" {
+			description += " dev " + route.Dev
+		}
+		for _, nexthop := range route.Nexthops {
+			description += " nexthop"
+			if nexthop.Via != "" {
+				description += " via " + nexthop.Via
+			}
+			if nexthop.Dev != "" {
+				description += " dev " + nexthop.Dev
+			}
+		}
+		elements = append(elements, fmt.Sprintf("%s table %d", description, route.Table))
+	}
+	for _, vlan := range config.Vlans {
+		elements = append(elements, fmt.Sprintf("vlan %s", vlan.Name))
+	}
+	for _, link := range config.Links {
+		elements = append(elements, fmt.Sprintf("%s %s", link.Type, link.Name))
+	}
+	for _, vrf := range config.Vrfs {
+		elements = append(elements, fmt.Sprintf("vrf %s table %d", vrf.Name, vrf.Table))
+	}
+	for _, neighbor := range config.Neighbors {
+		elements = append(elements, fmt.Sprintf("neighbor %s lladdr %s dev %s", neighbor.IP, neighbor.LLAddr, neighbor.Dev))
+	}
+	for _, entry := range config.Fdb {
+		elements = append(elements, fmt.Sprintf("fdb %s dst %s dev %s", entry.LLAddr, entry.Dst, entry.Dev))
+	}
+	for _, address := range config.Addresses {
+		elements = append(elements, fmt.Sprintf("address %s dev %s", address.Address, address.Dev))
+	}
+	for _, table := range config.Settings.TableHardSync {
+		elements = append(elements, fmt.Sprintf("table-hard-sync %d", table))
+	}
+	keys := make([]string, 0, len(config.Settings.Sysctls))
+	for key := range config.Settings.Sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		elements = append(elements, fmt.Sprintf("sysctl %s=%s", key, config.Settings.Sysctls[key]))
+	}
+	return strings.Join(elements, ", ")
+}
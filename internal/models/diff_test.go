@@ -0,0 +1,63 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffConfigModels", func() {
+	desired := &ConfigModel{
+		Settings: SettingsModel{TableHardSync: []int{102, 103}},
+		Rules: []RuleModel{
+			{From: "172.31.201.11/32", Table: 102},
+			{From: "172.31.201.12/32", Table: 103},
+		},
+		Routes: []RouteModel{
+			{To: "default", Via: "172.31.201.1", Table: 102},
+		},
+		Vlans: []VlanModel{
+			{Name: "eth2.104", Link: "eth2", ID: 104},
+		},
+	}
+
+	It("should ignore the order of the elements", func() {
+		actual := &ConfigModel{
+			Settings: SettingsModel{TableHardSync: []int{103, 102}},
+			Rules: []RuleModel{
+				{From: "172.31.201.12/32", Table: 103},
+				{From: "172.31.201.11/32", Table: 102},
+			},
+			Routes: desired.Routes,
+			Vlans:  desired.Vlans,
+		}
+		diff := DiffConfigModels(desired, actual)
+		Expect(diff.Empty()).To(BeTrue())
+		Expect(diff.String()).To(BeEmpty())
+	})
+
+	It("should report missing and unexpected elements", func() {
+		actual := &ConfigModel{
+			Settings: desired.Settings,
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", Table: 102},
+				{From: "172.31.201.13/32", Table: 103},
+			},
+			Vlans: desired.Vlans,
+		}
+		diff := DiffConfigModels(desired, actual)
+		Expect(diff.Empty()).To(BeFalse())
+		Expect(diff.Missing.Rules).To(Equal([]RuleModel{{From: "172.31.201.12/32", Table: 103}}))
+		Expect(diff.Missing.Routes).To(Equal(desired.Routes))
+		Expect(diff.Unexpected.Rules).To(Equal([]RuleModel{{From: "172.31.201.13/32", Table: 103}}))
+		Expect(diff.String()).To(Equal("missing rule from 172.31.201.12/32 table 103, route to default via 172.31.201.1 table 102; " +
+			"unexpected rule from 172.31.201.13/32 table 103"))
+	})
+
+	It("should report the sysctls set to another value", func() {
+		desired := &ConfigModel{Settings: SettingsModel{Sysctls: map[string]string{"net.ipv4.conf.all.rp_filter": "2"}}}
+		actual := &ConfigModel{Settings: SettingsModel{Sysctls: map[string]string{"net.ipv4.conf.all.rp_filter": "1"}}}
+		diff := DiffConfigModels(desired, actual)
+		Expect(diff.Empty()).To(BeFalse())
+		Expect(diff.String()).To(Equal("missing sysctl net.ipv4.conf.all.rp_filter=2; unexpected sysctl net.ipv4.conf.all.rp_filter=1"))
+	})
+})
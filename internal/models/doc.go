@@ -0,0 +1,3 @@
+// Package models holds the config the ipruler agents apply on the nodes, as it is embedded in the API types.
+// +kubebuilder:object:generate=true
+package models
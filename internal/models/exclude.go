@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ExcludeModel selects the rules, routes and VLANs of the ClusterConfigs a NodeConfig leaves out of its merged config.
+type ExcludeModel struct {
+	// Rules are matched by their from, table and priority
+	Rules []RuleModel `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// Routes are matched by their to and table
+	Routes []RouteModel `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// Vlans are matched by their name
+	Vlans []VlanModel `json:"vlans,omitempty" yaml:"vlans,omitempty"`
+	// Selector matches the labels of the rules, routes and VLANs to leave out
+	Selector *metav1.LabelSelector `json:"selector,omitempty" yaml:"selector,omitempty"`
+}
+
+// ExcludeEntries returns the config without the rules, routes and VLANs selected by exclude, along with the identities
+// of the entries left out. Entries are matched by the same identity a NodeConfig overrides them with, the table names
+// of both being resolved with the tables of the config. An invalid selector is an error rather than leaving out nothing.
+func ExcludeEntries(config *ConfigModel, exclude *ExcludeModel) (ConfigModel, []string, error) {
+	if exclude == nil {
+		return *config, nil, nil
+	}
+
+	selector := labels.Nothing()
+	if exclude.Selector != nil {
+		if isEmptySelector(exclude.Selector) {
+			return *config, nil, fmt.Errorf("the exclude selector has neither matchLabels nor matchExpressions")
+		}
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(exclude.Selector); err != nil {
+			return *config, nil, fmt.Errorf("invalid exclude selector: %w", err)
+		}
+	}
+
+	tables := TableIDs(config)
+	result := *config
+	resolveTableNames(&result, tables)
+	excludedEntries := ConfigModel{Rules: exclude.Rules, Routes: exclude.Routes}
+	resolveTableNames(&excludedEntries, tables)
+
+	var excluded []string
+	result.Rules = excludeEntries(result.Rules, excludedEntries.Rules, (*RuleModel).identity,
+		func(rule *RuleModel) map[string]string { return rule.Labels }, selector, &excluded)
+	result.Routes = excludeEntries(result.Routes, excludedEntries.Routes, (*RouteModel).identity,
+		func(route *RouteModel) map[string]string { return route.Labels }, selector, &excluded)
+	result.Vlans = excludeEntries(result.Vlans, exclude.Vlans, (*VlanModel).identity,
+		func(vlan *VlanModel) map[string]string { return vlan.Labels }, selector, &excluded)
+	return result, excluded, nil
+}
+
+// isEmptySelector tells whether the selector would match every entry carrying labels
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
+
+// excludeEntries leaves out the entries sharing their identity with one of the excluded entries or whose labels match
+// the selector, and adds their identities to excluded.
+func excludeEntries[T any](entries []T, excludedEntries []T, identity func(*T) string, entryLabels func(*T) map[string]string,
+	selector labels.Selector, excluded *[]string) []T {
+	identities := make(map[string]bool)
+	for i := range excludedEntries {
+		identities[identity(&excludedEntries[i])] = true
+	}
+
+	var result []T
+	for i := range entries {
+		entry := &entries[i]
+		if identities[identity(entry)] || selector.Matches(labels.Set(entryLabels(entry))) {
+			*excluded = append(*excluded, identity(entry))
+			continue
+		}
+		result = append(result, *entry)
+	}
+	return result
+}
+
+// ValidateExclude checks that the excluded entries can be identified and that the selector is valid.
+func ValidateExclude(exclude *ExcludeModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if exclude == nil {
+		return allErrs
+	}
+
+	for i, rule := range exclude.Rules {
+		allErrs = append(allErrs, validateTable(rule.Table, fldPath.Child("rules").Index(i).Child("table"))...)
+	}
+	for i, route := range exclude.Routes {
+		if route.To == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("routes").Index(i).Child("to"), ""))
+		}
+		allErrs = append(allErrs, validateTable(route.Table, fldPath.Child("routes").Index(i).Child("table"))...)
+	}
+	for i, vlan := range exclude.Vlans {
+		if vlan.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("vlans").Index(i).Child("name"), ""))
+		}
+	}
+	if exclude.Selector != nil && isEmptySelector(exclude.Selector) {
+		allErrs = append(allErrs, field.Required(fldPath.Child("selector"), "must have matchLabels or matchExpressions, an empty selector matches every entry with labels"))
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(exclude.Selector, metav1validation.LabelSelectorValidationOptions{},
+		fldPath.Child("selector"))...)
+
+	return allErrs
+}
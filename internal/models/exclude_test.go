@@ -0,0 +1,110 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("ExcludeEntries", func() {
+	config := &ConfigModel{
+		Rules: []RuleModel{
+			{From: "172.31.201.11/32", Table: 102, Priority: 100},
+			{From: "172.31.201.12/32", Table: 103, Labels: map[string]string{"interface": "eth2"}},
+		},
+		Routes: []RouteModel{
+			{To: "default", Via: "172.31.201.1", Table: 102},
+			{To: "10.0.0.0/8", Via: "172.31.201.1"},
+		},
+		Vlans: []VlanModel{
+			{Name: "eth2.104", Link: "eth2", ID: 104, Labels: map[string]string{"interface": "eth2"}},
+			{Name: "eth3.105", Link: "eth3", ID: 105},
+		},
+	}
+
+	It("should leave out the entries with the same identity", func() {
+		excludedConfig, excluded, err := ExcludeEntries(config, &ExcludeModel{
+			Rules:  []RuleModel{{From: "172.31.201.11/32", Table: 102, Priority: 100}},
+			Routes: []RouteModel{{To: "0.0.0.0/0", Table: 102}, {To: "10.0.0.0/8", TableName: "main"}},
+			Vlans:  []VlanModel{{Name: "eth3.105"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excludedConfig.Rules).To(Equal(config.Rules[1:]))
+		Expect(excludedConfig.Routes).To(BeEmpty())
+		Expect(excludedConfig.Vlans).To(Equal(config.Vlans[:1]))
+		Expect(excluded).To(Equal([]string{
+			"rule from 172.31.201.11/32 table 102 priority 100",
+			"route to default table 102",
+			"route to 10.0.0.0/8 table 254",
+			"vlan eth3.105",
+		}))
+	})
+
+	It("should leave out the entries whose labels match the selector", func() {
+		excludedConfig, excluded, err := ExcludeEntries(config, &ExcludeModel{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"interface": "eth2"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excludedConfig.Rules).To(Equal(config.Rules[:1]))
+		Expect(excludedConfig.Routes).To(Equal(config.Routes))
+		Expect(excludedConfig.Vlans).To(Equal(config.Vlans[1:]))
+		Expect(excluded).To(Equal([]string{"rule from 172.31.201.12/32 table 103 priority 0", "vlan eth2.104"}))
+	})
+
+	It("should leave the config as is without an exclude block", func() {
+		excludedConfig, excluded, err := ExcludeEntries(config, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excludedConfig).To(Equal(*config))
+		Expect(excluded).To(BeEmpty())
+	})
+
+	It("should match the tables by id whether they are referred to by name or by id", func() {
+		named := &ConfigModel{
+			Tables: []TableModel{{Name: "isp", ID: 102}},
+			Rules:  []RuleModel{{From: "172.31.201.11/32", TableName: "isp"}},
+			Routes: []RouteModel{{To: "default", Via: "172.31.201.1", TableName: "isp"}, {To: "default", Via: "172.31.201.1", Table: 103}},
+		}
+		excludedConfig, excluded, err := ExcludeEntries(named, &ExcludeModel{
+			Rules:  []RuleModel{{From: "172.31.201.11/32", Table: 102}},
+			Routes: []RouteModel{{To: "default", Table: 102}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(excludedConfig.Rules).To(BeEmpty())
+		Expect(excludedConfig.Routes).To(Equal(named.Routes[1:]))
+		Expect(excluded).To(Equal([]string{"rule from 172.31.201.11/32 table 102 priority 0", "route to default table 102"}))
+	})
+
+	It("should fail on empty and invalid selectors rather than leaving out everything or nothing", func() {
+		_, _, err := ExcludeEntries(config, &ExcludeModel{Selector: &metav1.LabelSelector{}})
+		Expect(err).To(HaveOccurred())
+		_, _, err = ExcludeEntries(config, &ExcludeModel{Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "interface", Operator: "Unknown"},
+		}}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject excluded entries that can't be identified and invalid selectors", func() {
+		errs := ValidateExclude(&ExcludeModel{
+			Routes: []RouteModel{{Table: 102}},
+			Vlans:  []VlanModel{{Link: "eth2"}},
+			Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "interface", Operator: metav1.LabelSelectorOpIn},
+			}},
+		}, field.NewPath("spec", "exclude"))
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.exclude.routes[0].to",
+			"spec.exclude.vlans[0].name",
+			"spec.exclude.selector.matchExpressions[0].values",
+		}))
+
+		errs = ValidateExclude(&ExcludeModel{Selector: &metav1.LabelSelector{}}, field.NewPath("spec", "exclude"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("spec.exclude.selector"))
+	})
+})
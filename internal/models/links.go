@@ -0,0 +1,75 @@
+package models
+
+// dependencies returns the names of the interfaces the link is created on top of, or enslaves.
+func (link *LinkModel) dependencies() []string {
+	var names []string
+	if link.Bond != nil {
+		names = append(names, link.Bond.Slaves...)
+	}
+	if link.Bridge != nil {
+		names = append(names, link.Bridge.Ports...)
+	}
+	if link.Macvlan != nil {
+		names = append(names, link.Macvlan.Link)
+	}
+	if link.Ipvlan != nil {
+		names = append(names, link.Ipvlan.Link)
+	}
+	if link.Vlan != nil {
+		names = append(names, link.Vlan.Link)
+	}
+	// the underlay interfaces of the tunnels are optional
+	if link.Vxlan != nil && link.Vxlan.Dev != "" {
+		names = append(names, link.Vxlan.Dev)
+	}
+	for _, tunnel := range []*TunnelModel{link.Gre, link.Ipip} {
+		if tunnel != nil && tunnel.Dev != "" {
+			names = append(names, tunnel.Dev)
+		}
+	}
+	return names
+}
+
+// orderLinks sorts the links so that every link comes after the other links it depends on, keeping the given order
+// otherwise. Interfaces that are not in the list, like the physical ones, are expected to exist already.
+// The links that can't be ordered, as they depend on each other in a cycle, are left last in the given order and
+// their names are returned.
+func orderLinks(links []LinkModel) ([]LinkModel, []string) {
+	pending := map[string]bool{}
+	for _, link := range links {
+		pending[link.Name] = true
+	}
+
+	ordered := make([]LinkModel, 0, len(links))
+	done := make([]bool, len(links))
+	for progressed := true; progressed; {
+		progressed = false
+		for i := range links {
+			if done[i] || !dependenciesCreated(&links[i], pending) {
+				continue
+			}
+			ordered = append(ordered, links[i])
+			done[i] = true
+			delete(pending, links[i].Name)
+			progressed = true
+		}
+	}
+
+	var cycle []string
+	for i := range links {
+		if !done[i] {
+			ordered = append(ordered, links[i])
+			cycle = append(cycle, links[i].Name)
+		}
+	}
+	return ordered, cycle
+}
+
+func dependenciesCreated(link *LinkModel, pending map[string]bool) bool {
+	for _, name := range link.dependencies() {
+		if pending[name] {
+			return false
+		}
+	}
+	return true
+}
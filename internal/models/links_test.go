@@ -0,0 +1,39 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("orderLinks", func() {
+	names := func(links []LinkModel) []string {
+		result := []string{}
+		for _, link := range links {
+			result = append(result, link.Name)
+		}
+		return result
+	}
+
+	It("should create the links after the links they depend on", func() {
+		links := []LinkModel{
+			{Name: "bond0.104", Type: "vlan", Vlan: &VlanModel{Link: "bond0", ID: 104}},
+			{Name: "dummy0", Type: "dummy"},
+			{Name: "br0", Type: "bridge", Bridge: &BridgeModel{Ports: []string{"bond0.104"}}},
+			{Name: "bond0", Type: "bond", Bond: &BondModel{Mode: "802.3ad", Slaves: []string{"eth0", "eth1"}}},
+		}
+		ordered, cycle := orderLinks(links)
+		Expect(cycle).To(BeEmpty())
+		Expect(names(ordered)).To(Equal([]string{"dummy0", "bond0", "bond0.104", "br0"}))
+	})
+
+	It("should leave the links depending on each other in a cycle last", func() {
+		links := []LinkModel{
+			{Name: "macvlan0", Type: "macvlan", Macvlan: &MacvlanModel{Link: "macvlan1"}},
+			{Name: "macvlan1", Type: "macvlan", Macvlan: &MacvlanModel{Link: "macvlan0"}},
+			{Name: "dummy0", Type: "dummy"},
+		}
+		ordered, cycle := orderLinks(links)
+		Expect(cycle).To(Equal([]string{"macvlan0", "macvlan1"}))
+		Expect(names(ordered)).To(Equal([]string{"dummy0", "macvlan0", "macvlan1"}))
+	})
+})
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// builtinTables are the tables of the kernel, known by name without being defined
+var builtinTables = map[string]int{"default": tableDefault, "main": tableMain, "local": tableLocal}
+
+// tableNamePattern is what iproute2 accepts as the name of a table
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// TableIDs returns the ids of the tables named by the configs, along with the builtin ones.
+func TableIDs(configs ...*ConfigModel) map[string]int {
+	tables := map[string]int{}
+	for name, id := range builtinTables {
+		tables[name] = id
+	}
+	for _, config := range configs {
+		for _, table := range config.Tables {
+			tables[table.Name] = table.ID
+		}
+	}
+	return tables
+}
+
+// ResolveTableNames replaces the table names the rules and routes of the config refer to with the ids of its tables,
+// and adds the tables to hard sync to its settings. The rules and routes referring to unknown tables are left out,
+// rather than being sent to the main table, and their table names are returned.
+func ResolveTableNames(config *ConfigModel) []string {
+	unknown := resolveTableNames(config, TableIDs(config))
+	config.Rules = slices.DeleteFunc(config.Rules, func(rule RuleModel) bool { return rule.TableName != "" })
+	config.Routes = slices.DeleteFunc(config.Routes, func(route RouteModel) bool { return route.TableName != "" })
+
+	for _, table := range config.Tables {
+		if table.HardSync && !slices.Contains(config.Settings.TableHardSync, table.ID) {
+			config.Settings.TableHardSync = append(config.Settings.TableHardSync, table.ID)
+		}
+	}
+	// the agents only deal with table ids
+	config.Tables = nil
+
+	return unknown
+}
+
+// resolveTableNames makes the rules and routes of the config refer to the given tables by id, so that a table is
+// identified the same way whether it is referred to by name or by id. The rules and routes are copied rather than
+// modified in place. The ones referring to unknown tables are kept as is, and their table names are returned.
+func resolveTableNames(config *ConfigModel, tables map[string]int) []string {
+	var unknown []string
+
+	config.Rules = slices.Clone(config.Rules)
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.TableName == "" {
+			continue
+		}
+		if id, ok := tables[rule.TableName]; ok {
+			rule.Table, rule.TableName = id, ""
+		} else {
+			unknown = append(unknown, rule.TableName)
+		}
+	}
+
+	config.Routes = slices.Clone(config.Routes)
+	for i := range config.Routes {
+		route := &config.Routes[i]
+		if route.TableName == "" {
+			continue
+		}
+		if id, ok := tables[route.TableName]; ok {
+			route.Table, route.TableName = id, ""
+		} else {
+			unknown = append(unknown, route.TableName)
+		}
+	}
+
+	return unknown
+}
+
+// validateTables checks the names and ids of the tables defined by the config. As rules and routes may refer to tables
+// defined by other configs, they are checked against the merged tables with ValidateTableNames and ValidateTableIDs.
+func validateTables(config *ConfigModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	names := map[string]bool{}
+	ids := map[int]string{}
+	for i, table := range config.Tables {
+		tablePath := fldPath.Child("tables").Index(i)
+		switch _, builtin := builtinTables[table.Name]; {
+		case table.Name == "":
+			allErrs = append(allErrs, field.Required(tablePath.Child("name"), ""))
+		case builtin:
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("name"), table.Name, "is the name of a table of the kernel"))
+		case !tableNamePattern.MatchString(table.Name):
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("name"), table.Name, "must start with a letter and consist of letters, digits, '_', '.' and '-'"))
+		case names[table.Name]:
+			allErrs = append(allErrs, field.Duplicate(tablePath.Child("name"), table.Name))
+		}
+		names[table.Name] = true
+
+		switch {
+		case table.ID <= 0 || table.ID > maxUint32:
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("id"), table.ID, "must be a positive 32 bit unsigned integer"))
+		case table.ID == tableDefault || table.ID == tableMain || table.ID == tableLocal:
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("id"), table.ID, "must not be the id of the default, main or local table"))
+		case ids[table.ID] != "":
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("id"), table.ID, fmt.Sprintf("is already the id of table %s", ids[table.ID])))
+		default:
+			ids[table.ID] = table.Name
+		}
+	}
+
+	return allErrs
+}
+
+// ValidateTableNames checks that the rules and routes of the config refer to the given tables by name.
+func ValidateTableNames(config *ConfigModel, tables map[string]int, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, rule := range config.Rules {
+		if _, ok := tables[rule.TableName]; rule.TableName != "" && !ok {
+			allErrs = append(allErrs, field.NotFound(fldPath.Child("rules").Index(i).Child("table-name"), rule.TableName))
+		}
+	}
+	for i, route := range config.Routes {
+		if _, ok := tables[route.TableName]; route.TableName != "" && !ok {
+			allErrs = append(allErrs, field.NotFound(fldPath.Child("routes").Index(i).Child("table-name"), route.TableName))
+		}
+	}
+	return allErrs
+}
+
+// ValidateTableIDs checks that the tables of the config don't give another id to the tables defined by the configs it
+// is merged with, nor another name to their ids.
+func ValidateTableIDs(config *ConfigModel, others []*ConfigModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	ids := map[string]int{}
+	names := map[int]string{}
+	for _, other := range others {
+		for _, table := range other.Tables {
+			ids[table.Name] = table.ID
+			names[table.ID] = table.Name
+		}
+	}
+	for i, table := range config.Tables {
+		tablePath := fldPath.Child("tables").Index(i)
+		if id, ok := ids[table.Name]; ok && id != table.ID {
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("id"), table.ID, fmt.Sprintf("table %s is already defined with id %d", table.Name, id)))
+		} else if name, ok := names[table.ID]; ok && name != table.Name {
+			allErrs = append(allErrs, field.Invalid(tablePath.Child("id"), table.ID, fmt.Sprintf("is already the id of table %s", name)))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Tables", func() {
+	fldPath := field.NewPath("spec", "config")
+	fields := func(errs field.ErrorList) []string {
+		result := []string{}
+		for _, err := range errs {
+			result = append(result, err.Field)
+		}
+		return result
+	}
+
+	It("should resolve the table names of the rules and routes", func() {
+		config := &ConfigModel{
+			Tables: []TableModel{
+				{Name: "isp1", ID: 101},
+				{Name: "isp2", ID: 102, HardSync: true},
+			},
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", TableName: "isp1"},
+				{From: "172.31.201.12/32", TableName: "isp3"},
+				{From: "172.31.201.13/32", Table: 103},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", TableName: "isp2"},
+				{To: "10.0.0.0/8", Via: "172.31.201.1", TableName: "main"},
+			},
+		}
+		unknown := ResolveTableNames(config)
+		Expect(unknown).To(Equal([]string{"isp3"}))
+		Expect(config.Tables).To(BeNil())
+		Expect(config.Settings.TableHardSync).To(Equal([]int{102}))
+		Expect(config.Rules).To(Equal([]RuleModel{
+			{From: "172.31.201.11/32", Table: 101},
+			{From: "172.31.201.13/32", Table: 103},
+		}))
+		Expect(config.Routes).To(Equal([]RouteModel{
+			{To: "default", Via: "172.31.201.1", Table: 102},
+			{To: "10.0.0.0/8", Via: "172.31.201.1", Table: tableMain},
+		}))
+	})
+
+	It("should reject invalid table names and ids", func() {
+		config := &ConfigModel{
+			Tables: []TableModel{
+				{Name: "isp1", ID: 101},
+				{Name: "main", ID: 104},
+				{Name: "1isp", ID: 105},
+				{Name: "isp1", ID: 106},
+				{Name: "isp2", ID: 254},
+				{Name: "isp3", ID: 101},
+				{Name: "isp4", ID: 0},
+			},
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", TableName: "isp1", Table: 101},
+				{From: "172.31.201.12/32", TableName: "isp1", Action: "blackhole"},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", TableName: "isp1", Table: 101},
+			},
+		}
+		Expect(fields(ValidateConfigModel(config, fldPath))).To(Equal([]string{
+			"spec.config.rules[0].table-name",
+			"spec.config.rules[1].table-name",
+			"spec.config.routes[0].table-name",
+			"spec.config.tables[1].name",
+			"spec.config.tables[2].name",
+			"spec.config.tables[3].name",
+			"spec.config.tables[4].id",
+			"spec.config.tables[5].id",
+			"spec.config.tables[6].id",
+		}))
+	})
+
+	It("should reject unknown table names and ids colliding with the merged tables", func() {
+		cluster := &ConfigModel{Tables: []TableModel{{Name: "isp1", ID: 101}}}
+		config := &ConfigModel{
+			Tables: []TableModel{
+				{Name: "isp1", ID: 102},
+				{Name: "isp2", ID: 101},
+				{Name: "isp3", ID: 103},
+			},
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", TableName: "isp1"},
+				{From: "172.31.201.12/32", TableName: "isp3"},
+				{From: "172.31.201.13/32", TableName: "isp4"},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", TableName: "local"},
+			},
+		}
+		Expect(fields(ValidateTableIDs(config, []*ConfigModel{cluster}, fldPath))).To(Equal([]string{
+			"spec.config.tables[0].id",
+			"spec.config.tables[1].id",
+		}))
+		Expect(fields(ValidateTableNames(config, TableIDs(cluster, config), fldPath))).To(Equal([]string{
+			"spec.config.rules[2].table-name",
+		}))
+	})
+})
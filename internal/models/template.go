@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// NodeInfo is what the templates of a config refer to, e.g. {{ index .Annotations "ipruler.pegah.tech/eth2.104" }}.
+// +kubebuilder:object:generate=false
+type NodeInfo struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// IsTemplate reports whether the value has to be rendered for every node.
+func IsTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// RenderConfigModel returns a copy of the config with the templates of its addresses rendered for the node.
+// The rendered addresses are validated, as they could not be before.
+func RenderConfigModel(config *ConfigModel, node *NodeInfo) (*ConfigModel, error) {
+	rendered := config.DeepCopy()
+	allErrs := field.ErrorList{}
+	for i := range rendered.Addresses {
+		address := &rendered.Addresses[i]
+		fldPath := field.NewPath("addresses").Index(i)
+		templates := []struct {
+			name  string
+			value *string
+		}{{"address", &address.Address}, {"peer", &address.Peer}, {"label", &address.Label}}
+		for _, t := range templates {
+			if err := renderTemplate(t.value, node); err != nil {
+				return nil, fmt.Errorf("failed to render %s: %w", fldPath.Child(t.name), err)
+			}
+		}
+		allErrs = append(allErrs, validateAddress(address, fldPath)...)
+	}
+	if len(allErrs) > 0 {
+		return nil, allErrs.ToAggregate()
+	}
+	return rendered, nil
+}
+
+func renderTemplate(value *string, node *NodeInfo) error {
+	if !IsTemplate(*value) {
+		return nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(*value)
+	if err != nil {
+		return err
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, node); err != nil {
+		return err
+	}
+	*value = strings.TrimSpace(rendered.String())
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenderConfigModel", func() {
+	node := &NodeInfo{
+		Name:        "worker-1",
+		Labels:      map[string]string{"networking.type": "eth2-vlan-104"},
+		Annotations: map[string]string{"ipruler.pegah.tech/eth2.104": "172.31.201.11"},
+	}
+
+	It("should render the addresses for the node", func() {
+		config := &ConfigModel{
+			Addresses: []AddressModel{
+				{Dev: "eth2.104", Address: `{{ index .Annotations "ipruler.pegah.tech/eth2.104" }}/24`, Label: "eth2.104:{{ len .Name }}"},
+				{Dev: "lo", Address: "10.255.0.1/32"},
+			},
+		}
+		rendered, err := RenderConfigModel(config, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rendered.Addresses).To(Equal([]AddressModel{
+			{Dev: "eth2.104", Address: "172.31.201.11/24", Label: "eth2.104:8"},
+			{Dev: "lo", Address: "10.255.0.1/32"},
+		}))
+		// the config itself is left as is
+		Expect(config.Addresses[0].Address).To(HavePrefix("{{"))
+	})
+
+	It("should reject addresses that are invalid once rendered", func() {
+		config := &ConfigModel{
+			Addresses: []AddressModel{
+				{Dev: "eth2.104", Address: `{{ index .Annotations "ipruler.pegah.tech/eth2.105" }}/24`},
+			},
+		}
+		_, err := RenderConfigModel(config, node)
+		Expect(err).To(MatchError(ContainSubstring("addresses[0].address")))
+	})
+
+	It("should fail on templates referring to missing fields", func() {
+		config := &ConfigModel{
+			Addresses: []AddressModel{
+				{Dev: "eth2.104", Address: "{{ .Zone }}/24"},
+			},
+		}
+		_, err := RenderConfigModel(config, node)
+		Expect(err).To(MatchError(ContainSubstring("failed to render addresses[0].address")))
+	})
+})
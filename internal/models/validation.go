@@ -0,0 +1,883 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	vlanIDMin = 1
+	vlanIDMax = 4094
+
+	// IFNAMSIZ - 1, the longest interface name the kernel accepts
+	maxInterfaceNameLength = 15
+
+	maxUint32 = 1<<32 - 1
+
+	// the smallest MTU of IPv4 and the largest one of any link
+	minMTU = 68
+	maxMTU = 65535
+
+	// the default, main and local tables of the kernel, which a VRF may not be bound to
+	tableDefault = 253
+	tableMain    = 254
+	tableLocal   = 255
+
+	vxlanVNIMax = 1<<24 - 1
+	maxPort     = 65535
+	// the length of a base64 encoded WireGuard key
+	wireguardKeyLength = 44
+
+	nexthopWeightMin = 1
+	nexthopWeightMax = 256
+)
+
+// routeScopes are the names iproute2 accepts for a route scope
+var routeScopes = []string{"global", "universe", "site", "link", "host", "nowhere"}
+
+// routeProtocols are the names iproute2 knows from rt_protos
+var routeProtocols = []string{
+	"redirect", "kernel", "boot", "static", "gated", "ra", "mrt", "zebra", "bird", "dnrouted", "xorp", "ntk",
+	"dhcp", "keepalived", "babel", "openr", "bgp", "isis", "ospf", "rip", "eigrp",
+}
+
+// routeTypes are the route types the agents support, unicast being the default
+var routeTypes = []string{"unicast", "blackhole", "unreachable", "prohibit", "local", "throw"}
+
+// ruleActions are the actions of an ip rule, lookup being the default
+var ruleActions = []string{"lookup", "blackhole", "unreachable", "prohibit", "goto"}
+
+// ipProtocols are the names iproute2 accepts for the ipproto selector, besides protocol numbers
+var ipProtocols = []string{"tcp", "udp", "icmp", "ipv6-icmp", "sctp", "gre", "esp", "ah"}
+
+// addressFamilies are the families a rule or a route may be given explicitly
+var addressFamilies = []string{FamilyIPv4, FamilyIPv6}
+
+// linkTypes are the types of the links the agents create
+var linkTypes = []string{"bond", "bridge", "dummy", "macvlan", "ipvlan", "vlan", "vxlan", "gre", "ipip", "wireguard"}
+
+// bondModes are the bonding modes of the kernel
+var bondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+
+// macvlanModes and ipvlanModes are the modes of the macvlan and ipvlan sub-interfaces
+var (
+	macvlanModes = []string{"private", "vepa", "bridge", "passthru", "source"}
+	ipvlanModes  = []string{"l2", "l3", "l3s"}
+)
+
+// allowedSysctls are the kernel parameters the config may set, where * stands for the name of an interface
+// (or all and default), with its dots written as slashes like in net.ipv4.conf.eth2/104.rp_filter
+var allowedSysctls = []string{
+	"net.ipv4.ip_forward",
+	"net.ipv4.fib_multipath_hash_policy",
+	"net.ipv4.fib_multipath_use_neigh",
+	"net.ipv4.tcp_l3mdev_accept",
+	"net.ipv4.udp_l3mdev_accept",
+	"net.ipv4.conf.*.rp_filter",
+	"net.ipv4.conf.*.arp_ignore",
+	"net.ipv4.conf.*.arp_announce",
+	"net.ipv4.conf.*.arp_filter",
+	"net.ipv4.conf.*.accept_local",
+	"net.ipv4.conf.*.forwarding",
+	"net.ipv4.conf.*.proxy_arp",
+	"net.ipv4.conf.*.src_valid_mark",
+	"net.ipv4.conf.*.accept_redirects",
+	"net.ipv4.conf.*.send_redirects",
+	"net.ipv6.fib_multipath_hash_policy",
+	"net.ipv6.conf.*.forwarding",
+	"net.ipv6.conf.*.accept_ra",
+	"net.ipv6.conf.*.disable_ipv6",
+}
+
+// neighborStates are the states of the neighbor entries the operator manages
+var neighborStates = []string{"permanent", "noarp", "reachable", "stale"}
+
+// addressScopes are the names iproute2 accepts for the scope of an address
+var addressScopes = []string{"global", "site", "link", "host"}
+
+// vlanProtocols are the VLAN protocols the kernel supports
+var vlanProtocols = []string{"802.1Q", "802.1ad"}
+
+// ValidateConfigModel checks the content of a ConfigModel before it is handed over to the agents.
+func ValidateConfigModel(config *ConfigModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, rule := range config.Rules {
+		allErrs = append(allErrs, validateRule(&rule, fldPath.Child("rules").Index(i))...)
+	}
+	for i, route := range config.Routes {
+		allErrs = append(allErrs, validateRoute(&route, fldPath.Child("routes").Index(i))...)
+	}
+	for i, vlan := range config.Vlans {
+		allErrs = append(allErrs, validateVlan(&vlan, fldPath.Child("vlans").Index(i))...)
+	}
+	allErrs = append(allErrs, validateTables(config, fldPath)...)
+	allErrs = append(allErrs, validateLinks(config, fldPath)...)
+	allErrs = append(allErrs, validateVrfs(config, fldPath)...)
+	for i, neighbor := range config.Neighbors {
+		allErrs = append(allErrs, validateNeighbor(&neighbor, fldPath.Child("neighbors").Index(i))...)
+	}
+	for i, fdb := range config.Fdb {
+		allErrs = append(allErrs, validateFdb(&fdb, config, fldPath.Child("fdb").Index(i))...)
+	}
+	for i, address := range config.Addresses {
+		allErrs = append(allErrs, validateAddress(&address, fldPath.Child("addresses").Index(i))...)
+	}
+	sysctls := make([]string, 0, len(config.Settings.Sysctls))
+	for key := range config.Settings.Sysctls {
+		sysctls = append(sysctls, key)
+	}
+	slices.Sort(sysctls)
+	for _, key := range sysctls {
+		allErrs = append(allErrs, validateSysctl(key, config.Settings.Sysctls[key], fldPath.Child("settings", "sysctls").Key(key))...)
+	}
+	for i, table := range config.Settings.TableHardSync {
+		allErrs = append(allErrs, validateTable(table, fldPath.Child("settings", "table-hard-sync").Index(i))...)
+	}
+
+	return allErrs
+}
+
+func validateRule(rule *RuleModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if rule.From != "" && rule.From != "all" && !isIPOrCIDR(rule.From) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("from"), rule.From, "must be an IP address, a CIDR or \"all\""))
+	}
+	if rule.To != "" && rule.To != "all" && !isIPOrCIDR(rule.To) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("to"), rule.To, "must be an IP address, a CIDR or \"all\""))
+	}
+	if rule.Family != "" && !slices.Contains(addressFamilies, rule.Family) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("family"), rule.Family, addressFamilies))
+	} else {
+		family := rule.AddressFamily()
+		allErrs = append(allErrs, validateAddressFamily(rule.From, family, fldPath.Child("from"))...)
+		allErrs = append(allErrs, validateAddressFamily(rule.To, family, fldPath.Child("to"))...)
+	}
+	allErrs = append(allErrs, validateTable(rule.Table, fldPath.Child("table"))...)
+	if rule.Priority < 0 || rule.Priority > maxUint32 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("priority"), rule.Priority, "must be a 32 bit unsigned integer"))
+	}
+	if rule.FwMark != "" && !isUint32(rule.FwMark) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("fwmark"), rule.FwMark, "must be a 32 bit unsigned integer, e.g. 0x10"))
+	}
+	if rule.FwMask != "" {
+		if rule.FwMark == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("fwmark"), "fwmask requires fwmark"))
+		}
+		if !isUint32(rule.FwMask) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("fwmask"), rule.FwMask, "must be a 32 bit unsigned integer, e.g. 0xff"))
+		}
+	}
+	if rule.IIF != "" {
+		allErrs = append(allErrs, validateInterfaceName(rule.IIF, fldPath.Child("iif"))...)
+	}
+	if rule.OIF != "" {
+		allErrs = append(allErrs, validateInterfaceName(rule.OIF, fldPath.Child("oif"))...)
+	}
+	if rule.Tos < 0 || rule.Tos > 255 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("tos"), rule.Tos, "must be between 0 and 255"))
+	}
+	if rule.IPProto != "" && !slices.Contains(ipProtocols, rule.IPProto) {
+		if proto, err := strconv.Atoi(rule.IPProto); err != nil || proto < 0 || proto > 255 {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("ipproto"), rule.IPProto, ipProtocols))
+		}
+	}
+	if rule.SPort != "" && !isRange(rule.SPort, maxPort) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("sport"), rule.SPort, "must be a port or a range of ports, e.g. 1000-2000"))
+	}
+	if rule.DPort != "" && !isRange(rule.DPort, maxPort) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("dport"), rule.DPort, "must be a port or a range of ports, e.g. 1000-2000"))
+	}
+	if rule.UIDRange != "" && !isRange(rule.UIDRange, maxUint32) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("uidrange"), rule.UIDRange, "must be a range of user ids, e.g. 1000-2000"))
+	}
+
+	switch {
+	case rule.Action != "" && !slices.Contains(ruleActions, rule.Action):
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("action"), rule.Action, ruleActions))
+	case rule.Action == "goto" && rule.Goto <= rule.Priority:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("goto"), rule.Goto, "must be greater than the priority of the rule"))
+	case rule.Action != "goto" && rule.Goto != 0:
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("goto"), "may only be set when action is goto"))
+	}
+	if rule.Action != "" && rule.Action != "lookup" && rule.Table != 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("table"), fmt.Sprintf("may not be set when action is %s", rule.Action)))
+	}
+	if rule.Action != "" && rule.Action != "lookup" && rule.TableName != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("table-name"), fmt.Sprintf("may not be set when action is %s", rule.Action)))
+	}
+	if rule.TableName != "" && rule.Table != 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("table-name"), "may not be set along with table"))
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabels(rule.Labels, fldPath.Child("labels"))...)
+
+	return allErrs
+}
+
+func validateRoute(route *RouteModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if route.To == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("to"), ""))
+	} else if route.To != "default" && !isIPOrCIDR(route.To) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("to"), route.To, "must be an IP address, a CIDR or \"default\""))
+	}
+	if route.Via != "" && net.ParseIP(route.Via) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("via"), route.Via, "must be an IP address"))
+	}
+	if route.Family != "" && !slices.Contains(addressFamilies, route.Family) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("family"), route.Family, addressFamilies))
+	} else {
+		family := route.AddressFamily()
+		allErrs = append(allErrs, validateAddressFamily(route.To, family, fldPath.Child("to"))...)
+		allErrs = append(allErrs, validateAddressFamily(route.Via, family, fldPath.Child("via"))...)
+		allErrs = append(allErrs, validateAddressFamily(route.Src, family, fldPath.Child("src"))...)
+		for i, nexthop := range route.Nexthops {
+			allErrs = append(allErrs, validateAddressFamily(nexthop.Via, family, fldPath.Child("nexthops").Index(i).Child("via"))...)
+		}
+	}
+	if route.Type != "" && !slices.Contains(routeTypes, route.Type) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), route.Type, routeTypes))
+	}
+	// blackhole, unreachable, prohibit and throw routes don't forward the packets anywhere
+	forwarding := route.Type == "" || route.Type == "unicast" || route.Type == "local"
+	switch {
+	case len(route.Nexthops) > 0 && (route.Via != "" || route.Dev != ""):
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("nexthops"), "may not be set along with via or dev"))
+	case len(route.Nexthops) > 0 && !forwarding:
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("nexthops"), fmt.Sprintf("may not be set for %s routes", route.Type)))
+	case forwarding && len(route.Nexthops) == 0 && route.Via == "" && route.Dev == "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("via"), "either via, dev or nexthops must be set"))
+	case !forwarding && route.Via != "":
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("via"), fmt.Sprintf("may not be set for %s routes", route.Type)))
+	}
+	for i, nexthop := range route.Nexthops {
+		allErrs = append(allErrs, validateNexthop(&nexthop, fldPath.Child("nexthops").Index(i))...)
+	}
+	if route.Src != "" && net.ParseIP(route.Src) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("src"), route.Src, "must be an IP address"))
+	}
+	if route.Metric < 0 || route.Metric > maxUint32 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("metric"), route.Metric, "must be a 32 bit unsigned integer"))
+	}
+	if route.MTU != 0 && (route.MTU < minMTU || route.MTU > maxMTU) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("mtu"), route.MTU, fmt.Sprintf("must be between %d and %d", minMTU, maxMTU)))
+	}
+	if route.AdvMSS < 0 || route.AdvMSS > maxMTU {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("advmss"), route.AdvMSS, fmt.Sprintf("must be between 0 and %d", maxMTU)))
+	}
+	if route.InitCwnd < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("initcwnd"), route.InitCwnd, "must not be negative"))
+	}
+	if route.Dev != "" {
+		allErrs = append(allErrs, validateInterfaceName(route.Dev, fldPath.Child("dev"))...)
+	}
+	if route.Scope != "" && !slices.Contains(routeScopes, route.Scope) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("scope"), route.Scope, routeScopes))
+	}
+	if route.Protocol != "" && !slices.Contains(routeProtocols, route.Protocol) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("protocol"), route.Protocol, routeProtocols))
+	}
+	allErrs = append(allErrs, validateTable(route.Table, fldPath.Child("table"))...)
+	if route.TableName != "" && route.Table != 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("table-name"), "may not be set along with table"))
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabels(route.Labels, fldPath.Child("labels"))...)
+
+	return allErrs
+}
+
+func validateNexthop(nexthop *NexthopModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if nexthop.Via == "" && nexthop.Dev == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("via"), "either via or dev must be set"))
+	}
+	if nexthop.Via != "" && net.ParseIP(nexthop.Via) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("via"), nexthop.Via, "must be an IP address"))
+	}
+	if nexthop.Dev != "" {
+		allErrs = append(allErrs, validateInterfaceName(nexthop.Dev, fldPath.Child("dev"))...)
+	}
+	if nexthop.Weight != 0 && (nexthop.Weight < nexthopWeightMin || nexthop.Weight > nexthopWeightMax) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("weight"), nexthop.Weight,
+			fmt.Sprintf("must be between %d and %d", nexthopWeightMin, nexthopWeightMax)))
+	}
+
+	return allErrs
+}
+
+func validateVlan(vlan *VlanModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateInterfaceName(vlan.Name, fldPath.Child("name"))...)
+	allErrs = append(allErrs, validateVlanSettings(vlan, fldPath)...)
+	allErrs = append(allErrs, metav1validation.ValidateLabels(vlan.Labels, fldPath.Child("labels"))...)
+
+	return allErrs
+}
+
+func validateVlanSettings(vlan *VlanModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateInterfaceName(vlan.Link, fldPath.Child("link"))...)
+	if vlan.ID < vlanIDMin || vlan.ID > vlanIDMax {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("id"), vlan.ID, fmt.Sprintf("must be between %d and %d", vlanIDMin, vlanIDMax)))
+	}
+	if vlan.Protocol != "" && !slices.Contains(vlanProtocols, vlan.Protocol) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("protocol"), vlan.Protocol, vlanProtocols))
+	}
+
+	return allErrs
+}
+
+// validateLinks checks the links along with the names they share with the VLANs and the order they can be created in.
+func validateLinks(config *ConfigModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	names := map[string]bool{}
+	for _, vlan := range config.Vlans {
+		names[vlan.Name] = true
+	}
+	enslaved := map[string]string{}
+	for i, link := range config.Links {
+		linkPath := fldPath.Child("links").Index(i)
+		allErrs = append(allErrs, validateLink(&link, linkPath)...)
+		if names[link.Name] {
+			allErrs = append(allErrs, field.Duplicate(linkPath.Child("name"), link.Name))
+		}
+		names[link.Name] = true
+
+		var slaves []string
+		if link.Bond != nil {
+			slaves = link.Bond.Slaves
+		} else if link.Bridge != nil {
+			slaves = link.Bridge.Ports
+		}
+		for _, slave := range slaves {
+			if master, ok := enslaved[slave]; ok && master != link.Name {
+				allErrs = append(allErrs, field.Invalid(linkPath.Child(link.Type), slave, fmt.Sprintf("is already enslaved to %s", master)))
+			}
+			enslaved[slave] = link.Name
+		}
+	}
+
+	if _, cycle := orderLinks(config.Links); len(cycle) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("links"), strings.Join(cycle, ", "), "links may not depend on each other in a cycle"))
+	}
+
+	return allErrs
+}
+
+func validateLink(link *LinkModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateInterfaceName(link.Name, fldPath.Child("name"))...)
+	if link.MTU != 0 && (link.MTU < minMTU || link.MTU > maxMTU) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("mtu"), link.MTU, fmt.Sprintf("must be between %d and %d", minMTU, maxMTU)))
+	}
+	if !slices.Contains(linkTypes, link.Type) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), link.Type, linkTypes))
+		return allErrs
+	}
+
+	// only the sub-struct named after the type may be set
+	settings := map[string]bool{"bond": link.Bond != nil, "bridge": link.Bridge != nil, "macvlan": link.Macvlan != nil,
+		"ipvlan": link.Ipvlan != nil, "vlan": link.Vlan != nil, "vxlan": link.Vxlan != nil, "gre": link.Gre != nil,
+		"ipip": link.Ipip != nil, "wireguard": link.Wireguard != nil}
+	for _, linkType := range linkTypes {
+		if settings[linkType] && linkType != link.Type {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child(linkType), fmt.Sprintf("may not be set for %s links", link.Type)))
+		}
+	}
+
+	switch link.Type {
+	case "bond":
+		if link.Bond == nil {
+			break
+		}
+		if link.Bond.Mode != "" && !slices.Contains(bondModes, link.Bond.Mode) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("bond", "mode"), link.Bond.Mode, bondModes))
+		}
+		if link.Bond.Miimon < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("bond", "miimon"), link.Bond.Miimon, "must not be negative"))
+		}
+		for i, slave := range link.Bond.Slaves {
+			allErrs = append(allErrs, validateInterfaceName(slave, fldPath.Child("bond", "slaves").Index(i))...)
+		}
+	case "bridge":
+		if link.Bridge == nil {
+			break
+		}
+		for i, port := range link.Bridge.Ports {
+			allErrs = append(allErrs, validateInterfaceName(port, fldPath.Child("bridge", "ports").Index(i))...)
+		}
+	case "macvlan":
+		if link.Macvlan == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("macvlan"), ""))
+			break
+		}
+		allErrs = append(allErrs, validateInterfaceName(link.Macvlan.Link, fldPath.Child("macvlan", "link"))...)
+		if link.Macvlan.Mode != "" && !slices.Contains(macvlanModes, link.Macvlan.Mode) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("macvlan", "mode"), link.Macvlan.Mode, macvlanModes))
+		}
+	case "ipvlan":
+		if link.Ipvlan == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("ipvlan"), ""))
+			break
+		}
+		allErrs = append(allErrs, validateInterfaceName(link.Ipvlan.Link, fldPath.Child("ipvlan", "link"))...)
+		if link.Ipvlan.Mode != "" && !slices.Contains(ipvlanModes, link.Ipvlan.Mode) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("ipvlan", "mode"), link.Ipvlan.Mode, ipvlanModes))
+		}
+	case "vlan":
+		if link.Vlan == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("vlan"), ""))
+			break
+		}
+		// the name of the VLAN is the name of the link
+		if link.Vlan.Name != "" && link.Vlan.Name != link.Name {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("vlan", "name"), link.Vlan.Name, "must be empty or the name of the link"))
+		}
+		allErrs = append(allErrs, validateVlanSettings(link.Vlan, fldPath.Child("vlan"))...)
+	case "vxlan":
+		if link.Vxlan == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("vxlan"), ""))
+			break
+		}
+		allErrs = append(allErrs, validateVxlan(link.Vxlan, fldPath.Child("vxlan"))...)
+	case "gre", "ipip":
+		tunnel := link.Gre
+		if link.Type == "ipip" {
+			tunnel = link.Ipip
+		}
+		if tunnel == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child(link.Type), ""))
+			break
+		}
+		allErrs = append(allErrs, validateTunnel(tunnel, link.Type, fldPath.Child(link.Type))...)
+	case "wireguard":
+		if link.Wireguard == nil {
+			allErrs = append(allErrs, field.Required(fldPath.Child("wireguard"), ""))
+			break
+		}
+		allErrs = append(allErrs, validateWireguard(link.Wireguard, fldPath.Child("wireguard"))...)
+	}
+
+	return allErrs
+}
+
+func validateVxlan(vxlan *VxlanModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if vxlan.VNI < 1 || vxlan.VNI > vxlanVNIMax {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("vni"), vxlan.VNI, fmt.Sprintf("must be between 1 and %d", vxlanVNIMax)))
+	}
+	allErrs = append(allErrs, validateEndpoints(vxlan.Local, vxlan.Remote, fldPath)...)
+	switch {
+	case vxlan.Group == "":
+	case vxlan.Remote != "":
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("group"), "may not be set along with remote"))
+	case net.ParseIP(vxlan.Group) == nil || !net.ParseIP(vxlan.Group).IsMulticast():
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("group"), vxlan.Group, "must be a multicast IP address"))
+	case vxlan.Dev == "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("dev"), "the underlay interface is required to join a multicast group"))
+	}
+	if vxlan.DstPort < 0 || vxlan.DstPort > maxPort {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("dstport"), vxlan.DstPort, fmt.Sprintf("must be between 0 and %d", maxPort)))
+	}
+	if vxlan.Dev != "" {
+		allErrs = append(allErrs, validateInterfaceName(vxlan.Dev, fldPath.Child("dev"))...)
+	}
+
+	return allErrs
+}
+
+func validateTunnel(tunnel *TunnelModel, tunnelType string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateEndpoints(tunnel.Local, tunnel.Remote, fldPath)...)
+	// IPIP only carries IPv4 over IPv4
+	if tunnelType == "ipip" {
+		allErrs = append(allErrs, validateAddressFamily(tunnel.Local, FamilyIPv4, fldPath.Child("local"))...)
+		if tunnel.Local == "" {
+			allErrs = append(allErrs, validateAddressFamily(tunnel.Remote, FamilyIPv4, fldPath.Child("remote"))...)
+		}
+	}
+	if tunnel.TTL < 0 || tunnel.TTL > 255 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("ttl"), tunnel.TTL, "must be between 0 and 255"))
+	}
+	if tunnel.Dev != "" {
+		allErrs = append(allErrs, validateInterfaceName(tunnel.Dev, fldPath.Child("dev"))...)
+	}
+
+	return allErrs
+}
+
+// validateEndpoints checks the local and remote addresses of a tunnel, which must belong to the same family.
+func validateEndpoints(local string, remote string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if local != "" && net.ParseIP(local) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("local"), local, "must be an IP address"))
+	}
+	if remote != "" && net.ParseIP(remote) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("remote"), remote, "must be an IP address"))
+	} else if local != "" && net.ParseIP(local) != nil {
+		allErrs = append(allErrs, validateAddressFamily(remote, addressFamily(local), fldPath.Child("remote"))...)
+	}
+	return allErrs
+}
+
+func validateWireguard(wireguard *WireguardModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	refPath := fldPath.Child("private-key-secret-ref")
+	if wireguard.PrivateKeySecretRef.Namespace == "" {
+		allErrs = append(allErrs, field.Required(refPath.Child("namespace"), ""))
+	}
+	if wireguard.PrivateKeySecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(refPath.Child("name"), ""))
+	}
+	if wireguard.PrivateKeySecretRef.Key == "" {
+		allErrs = append(allErrs, field.Required(refPath.Child("key"), ""))
+	}
+	if wireguard.ListenPort < 0 || wireguard.ListenPort > maxPort {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("listen-port"), wireguard.ListenPort, fmt.Sprintf("must be between 0 and %d", maxPort)))
+	}
+
+	for i, peer := range wireguard.Peers {
+		peerPath := fldPath.Child("peers").Index(i)
+		if key, err := base64.StdEncoding.DecodeString(peer.PublicKey); err != nil || len(peer.PublicKey) != wireguardKeyLength || len(key) != 32 {
+			allErrs = append(allErrs, field.Invalid(peerPath.Child("public-key"), peer.PublicKey, "must be a base64 encoded WireGuard key"))
+		}
+		if peer.Endpoint != "" {
+			if _, port, err := net.SplitHostPort(peer.Endpoint); err != nil || !isPort(port) {
+				allErrs = append(allErrs, field.Invalid(peerPath.Child("endpoint"), peer.Endpoint, "must be a host:port"))
+			}
+		}
+		for j, allowedIP := range peer.AllowedIPs {
+			if _, _, err := net.ParseCIDR(allowedIP); err != nil {
+				allErrs = append(allErrs, field.Invalid(peerPath.Child("allowed-ips").Index(j), allowedIP, "must be a CIDR"))
+			}
+		}
+		if peer.PersistentKeepalive < 0 || peer.PersistentKeepalive > maxPort {
+			allErrs = append(allErrs, field.Invalid(peerPath.Child("persistent-keepalive"), peer.PersistentKeepalive, fmt.Sprintf("must be between 0 and %d", maxPort)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateVrfs checks the VRFs along with the names they share with the other interfaces and the tables they are bound to.
+func validateVrfs(config *ConfigModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	names := map[string]bool{}
+	for _, vlan := range config.Vlans {
+		names[vlan.Name] = true
+	}
+	for _, link := range config.Links {
+		names[link.Name] = true
+	}
+	tables := map[int]string{}
+	enslaved := map[string]string{}
+	for i, vrf := range config.Vrfs {
+		vrfPath := fldPath.Child("vrfs").Index(i)
+		allErrs = append(allErrs, validateInterfaceName(vrf.Name, vrfPath.Child("name"))...)
+		if names[vrf.Name] {
+			allErrs = append(allErrs, field.Duplicate(vrfPath.Child("name"), vrf.Name))
+		}
+		names[vrf.Name] = true
+
+		switch {
+		case vrf.Table <= 0:
+			allErrs = append(allErrs, field.Required(vrfPath.Child("table"), "must be a positive routing table id"))
+		case vrf.Table == tableDefault || vrf.Table == tableMain || vrf.Table == tableLocal:
+			allErrs = append(allErrs, field.Invalid(vrfPath.Child("table"), vrf.Table, "must not be the default, main or local table"))
+		case tables[vrf.Table] != "":
+			allErrs = append(allErrs, field.Invalid(vrfPath.Child("table"), vrf.Table, fmt.Sprintf("is already bound to VRF %s", tables[vrf.Table])))
+		default:
+			tables[vrf.Table] = vrf.Name
+		}
+
+		for j, name := range vrf.Interfaces {
+			allErrs = append(allErrs, validateInterfaceName(name, vrfPath.Child("interfaces").Index(j))...)
+			if other, ok := enslaved[name]; ok {
+				allErrs = append(allErrs, field.Invalid(vrfPath.Child("interfaces").Index(j), name, fmt.Sprintf("is already enslaved into VRF %s", other)))
+			}
+			enslaved[name] = vrf.Name
+		}
+	}
+
+	allErrs = append(allErrs, ValidateHardSyncedTables(config, VrfTables(config), fldPath)...)
+	return allErrs
+}
+
+// VrfTables returns the tables the VRFs of the config are bound to, along with the names of the VRFs.
+func VrfTables(config *ConfigModel) map[int]string {
+	tables := map[int]string{}
+	for _, vrf := range config.Vrfs {
+		if vrf.Table > 0 {
+			tables[vrf.Table] = vrf.Name
+		}
+	}
+	return tables
+}
+
+// ValidateHardSyncedTables checks that the table-hard-sync of the config doesn't include the tables of the given VRFs,
+// which may be defined by another config merged with this one. The kernel adds the local and connected routes of
+// the interfaces enslaved into a VRF to its table, and the agents would remove them when syncing the table.
+func ValidateHardSyncedTables(config *ConfigModel, vrfTables map[int]string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, table := range config.Settings.TableHardSync {
+		if vrf, ok := vrfTables[table]; ok {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("settings", "table-hard-sync").Index(i), table,
+				fmt.Sprintf("is the table of VRF %s, the routes the kernel adds to it would be removed", vrf)))
+		}
+	}
+	return allErrs
+}
+
+// ValidateVrfRoutes checks that the VRFs of the config, and the routes targeting their tables, don't collide with
+// the given hard synced tables, which may be defined by another config merged with this one.
+// The routes referring to a table by name are checked against the id the name has in the given tables.
+func ValidateVrfRoutes(config *ConfigModel, hardSyncedTables []int, tables map[string]int, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, vrf := range config.Vrfs {
+		if slices.Contains(hardSyncedTables, vrf.Table) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("vrfs").Index(i).Child("table"), vrf.Table,
+				"is hard synced by another config, the routes the kernel adds to it would be removed"))
+		}
+	}
+	vrfTables := VrfTables(config)
+	for i, route := range config.Routes {
+		table, tablePath, value := route.Table, fldPath.Child("routes").Index(i).Child("table"), any(route.Table)
+		if route.TableName != "" {
+			// unknown names are reported by ValidateTableNames
+			table, tablePath, value = tables[route.TableName], fldPath.Child("routes").Index(i).Child("table-name"), route.TableName
+		}
+		if vrf, ok := vrfTables[table]; ok && slices.Contains(hardSyncedTables, table) {
+			allErrs = append(allErrs, field.Invalid(tablePath, value,
+				fmt.Sprintf("is the table of VRF %s, which is hard synced by another config", vrf)))
+		}
+	}
+	return allErrs
+}
+
+func validateNeighbor(neighbor *NeighborModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateInterfaceName(neighbor.Dev, fldPath.Child("dev"))...)
+	if neighbor.IP == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("ip"), ""))
+	} else if net.ParseIP(neighbor.IP) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("ip"), neighbor.IP, "must be an IP address"))
+	}
+	allErrs = append(allErrs, validateLLAddr(neighbor.LLAddr, fldPath.Child("lladdr"))...)
+	if neighbor.State != "" && !slices.Contains(neighborStates, neighbor.State) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("state"), neighbor.State, neighborStates))
+	}
+
+	return allErrs
+}
+
+func validateFdb(fdb *FdbModel, config *ConfigModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateInterfaceName(fdb.Dev, fldPath.Child("dev"))...)
+	// the VXLAN link may as well be defined by another config
+	for _, link := range config.Links {
+		if link.Name == fdb.Dev && link.Type != "vxlan" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dev"), fdb.Dev, fmt.Sprintf("must be a vxlan link, not a %s link", link.Type)))
+		}
+	}
+	allErrs = append(allErrs, validateLLAddr(fdb.LLAddr, fldPath.Child("lladdr"))...)
+	if fdb.Dst == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("dst"), ""))
+	} else if net.ParseIP(fdb.Dst) == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("dst"), fdb.Dst, "must be an IP address"))
+	}
+	if fdb.VNI < 0 || fdb.VNI > vxlanVNIMax {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("vni"), fdb.VNI, fmt.Sprintf("must be between 0 and %d", vxlanVNIMax)))
+	}
+
+	return allErrs
+}
+
+func validateLLAddr(lladdr string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if lladdr == "" {
+		allErrs = append(allErrs, field.Required(fldPath, ""))
+	} else if _, err := net.ParseMAC(lladdr); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, lladdr, "must be a MAC address, e.g. 52:54:00:12:34:56"))
+	}
+	return allErrs
+}
+
+// validateAddress checks the address, leaving out the templates which are only validated once rendered for a node.
+func validateAddress(address *AddressModel, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateInterfaceName(address.Dev, fldPath.Child("dev"))...)
+	switch {
+	case address.Address == "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("address"), ""))
+	case IsTemplate(address.Address):
+		allErrs = append(allErrs, validateTemplate(address.Address, fldPath.Child("address"))...)
+	default:
+		if _, _, err := net.ParseCIDR(address.Address); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("address"), address.Address, "must be a CIDR, e.g. 172.31.201.11/24"))
+		}
+	}
+	switch {
+	case address.Peer == "":
+	case IsTemplate(address.Peer):
+		allErrs = append(allErrs, validateTemplate(address.Peer, fldPath.Child("peer"))...)
+	case !isIPOrCIDR(address.Peer):
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("peer"), address.Peer, "must be an IP address or a CIDR"))
+	case !IsTemplate(address.Address):
+		allErrs = append(allErrs, validateAddressFamily(address.Peer, addressFamily(address.Address), fldPath.Child("peer"))...)
+	}
+	switch {
+	case address.Label == "":
+	case IsTemplate(address.Label):
+		allErrs = append(allErrs, validateTemplate(address.Label, fldPath.Child("label"))...)
+	case len(address.Label) > maxInterfaceNameLength:
+		allErrs = append(allErrs, field.TooLong(fldPath.Child("label"), address.Label, maxInterfaceNameLength))
+	case address.Label != address.Dev && !strings.HasPrefix(address.Label, address.Dev+":"):
+		// the kernel only accepts labels prefixed with the name of the interface
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("label"), address.Label, fmt.Sprintf("must be %q or start with \"%s:\"", address.Dev, address.Dev)))
+	}
+	if address.Scope != "" && !slices.Contains(addressScopes, address.Scope) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("scope"), address.Scope, addressScopes))
+	}
+
+	return allErrs
+}
+
+func validateTemplate(value string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if _, err := template.New("").Parse(value); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, fmt.Sprintf("must be a valid template: %s", err)))
+	}
+	return allErrs
+}
+
+func validateSysctl(key string, value string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !sysctlAllowed(key) {
+		allErrs = append(allErrs, field.NotSupported(fldPath, key, allowedSysctls))
+	}
+	if _, err := strconv.Atoi(value); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, "must be an integer"))
+	}
+	return allErrs
+}
+
+// FilterSysctls leaves out the sysctls of the config that are not in the allowlist or whose values are not integers,
+// so that they never reach the agents even when the validating webhook is disabled. Their keys are returned sorted.
+func FilterSysctls(config *ConfigModel) []string {
+	var dropped []string
+	for key, value := range config.Settings.Sysctls {
+		if len(validateSysctl(key, value, field.NewPath("sysctls"))) > 0 {
+			dropped = append(dropped, key)
+		}
+	}
+	for _, key := range dropped {
+		delete(config.Settings.Sysctls, key)
+	}
+	slices.Sort(dropped)
+	return dropped
+}
+
+func sysctlAllowed(key string) bool {
+	for _, pattern := range allowedSysctls {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if key == pattern {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) || len(key) <= len(prefix)+len(suffix) {
+			continue
+		}
+		dev := key[len(prefix) : len(key)-len(suffix)]
+		if !strings.Contains(dev, ".") && len(dev) <= maxInterfaceNameLength {
+			return true
+		}
+	}
+	return false
+}
+
+func validateTable(table int, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if table < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, table, "must be a non-negative routing table id"))
+	}
+	return allErrs
+}
+
+// validateAddressFamily checks that the address, when it is one, belongs to the family of its rule or route.
+func validateAddressFamily(address string, family string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if addressFamily := addressFamily(address); addressFamily != "" && addressFamily != family {
+		allErrs = append(allErrs, field.Invalid(fldPath, address, fmt.Sprintf("must be an %s address like the rest of the entry", family)))
+	}
+	return allErrs
+}
+
+func validateInterfaceName(name string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if name == "" {
+		allErrs = append(allErrs, field.Required(fldPath, ""))
+	} else if len(name) > maxInterfaceNameLength {
+		allErrs = append(allErrs, field.TooLong(fldPath, name, maxInterfaceNameLength))
+	}
+	return allErrs
+}
+
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
+// isUint32 reports whether the value is a 32 bit unsigned integer, in decimal or in hex with the 0x prefix.
+func isUint32(value string) bool {
+	_, err := strconv.ParseUint(value, 0, 32)
+	return err == nil
+}
+
+// isRange reports whether the value is a number or a range of numbers like 1000-2000, none of them above limit.
+func isRange(value string, limit int) bool {
+	start, end, found := strings.Cut(value, "-")
+	if !found {
+		end = start
+	}
+	first, err := strconv.Atoi(start)
+	if err != nil {
+		return false
+	}
+	last, err := strconv.Atoi(end)
+	if err != nil {
+		return false
+	}
+	return first >= 0 && first <= last && last <= limit
+}
+
+func isPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port > 0 && port <= maxPort
+}
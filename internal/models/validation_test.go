@@ -0,0 +1,528 @@
+package models
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("ValidateConfigModel", func() {
+	fldPath := field.NewPath("spec", "config")
+
+	It("should accept a valid config", func() {
+		config := &ConfigModel{
+			Settings: SettingsModel{TableHardSync: []int{102}},
+			Rules: []RuleModel{
+				{From: "172.31.201.11/32", Table: 102},
+			},
+			Routes: []RouteModel{
+				{To: "172.31.201.0/24", Dev: "eth2.104", Scope: "link", Protocol: "static"},
+				{To: "default", Via: "172.31.201.1", Table: 102, Protocol: "static", OnLink: true},
+			},
+			Vlans: []VlanModel{
+				{Name: "eth2.104", Link: "eth2", ID: 104},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed addresses and tables in rules", func() {
+		config := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "172.31.201.300/32", Table: -1},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("spec.config.rules[0].from"))
+		Expect(errs[1].Field).To(Equal("spec.config.rules[0].table"))
+	})
+
+	It("should accept rules with the full set of selectors", func() {
+		config := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "172.31.201.0/24", To: "10.0.0.0/8", Table: 102, Priority: 100, FwMark: "0x10", FwMask: "0xff",
+					IIF: "eth2.104", Tos: 16, IPProto: "tcp", SPort: "1024-65535", DPort: "443", UIDRange: "1000-2000", Not: true},
+				{From: "172.31.201.11/32", Priority: 50, Action: "goto", Goto: 100},
+				{To: "192.168.0.0/16", Priority: 200, Action: "blackhole"},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed rule selectors", func() {
+		config := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "all", FwMask: "0xfffffffff", Tos: 256, IPProto: "carrier-pigeon", SPort: "2000-1000", DPort: "70000"},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.rules[0].fwmark",
+			"spec.config.rules[0].fwmask",
+			"spec.config.rules[0].tos",
+			"spec.config.rules[0].ipproto",
+			"spec.config.rules[0].sport",
+			"spec.config.rules[0].dport",
+		}))
+	})
+
+	It("should reject inconsistent rule actions", func() {
+		config := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "all", Priority: 100, Action: "goto", Goto: 50},
+				{From: "all", Table: 102, Goto: 200},
+				{From: "all", Table: 102, Action: "prohibit"},
+				{From: "all", Action: "nat"},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		Expect(errs).To(HaveLen(4))
+		Expect(errs[0].Field).To(Equal("spec.config.rules[0].goto"))
+		Expect(errs[1].Field).To(Equal("spec.config.rules[1].goto"))
+		Expect(errs[2].Field).To(Equal("spec.config.rules[2].table"))
+		Expect(errs[3].Type).To(Equal(field.ErrorTypeNotSupported))
+	})
+
+	It("should reject unknown route scopes and protocols", func() {
+		config := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Scope: "galaxy", Protocol: "carrier-pigeon"},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeNotSupported))
+		Expect(errs[1].Type).To(Equal(field.ErrorTypeNotSupported))
+	})
+
+	It("should accept routes with metrics, a preferred source and a type", func() {
+		config := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Table: 102, Metric: 100, Src: "172.31.201.11", MTU: 1450, AdvMSS: 1410, InitCwnd: 10},
+				{To: "10.0.0.0/8", Table: 102, Type: "unreachable"},
+				{To: "172.31.201.11", Dev: "eth2.104", Type: "local", Table: 255},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed route attributes", func() {
+		config := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Metric: -1, Src: "eth2", MTU: 10, AdvMSS: -1, InitCwnd: -1},
+				{To: "10.0.0.0/8", Via: "172.31.201.1", Type: "blackhole"},
+				{To: "10.0.0.0/8", Type: "anycast"},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.routes[0].src",
+			"spec.config.routes[0].metric",
+			"spec.config.routes[0].mtu",
+			"spec.config.routes[0].advmss",
+			"spec.config.routes[0].initcwnd",
+			"spec.config.routes[1].via",
+			"spec.config.routes[2].type",
+		}))
+	})
+
+	It("should accept a multipath route", func() {
+		config := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Table: 102, Nexthops: []NexthopModel{
+					{Via: "172.31.201.1", Dev: "eth2.104", Weight: 1},
+					{Via: "172.31.202.1", Dev: "eth2.105", Weight: 3},
+				}},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed multipath routes", func() {
+		config := &ConfigModel{
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.201.1", Nexthops: []NexthopModel{{Via: "172.31.202.1"}}},
+				{To: "default", Nexthops: []NexthopModel{{Via: "gateway", Weight: 300}, {}}},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.routes[0].nexthops",
+			"spec.config.routes[1].nexthops[0].via",
+			"spec.config.routes[1].nexthops[0].weight",
+			"spec.config.routes[1].nexthops[1].via",
+		}))
+	})
+
+	It("should accept IPv6 rules and routes", func() {
+		config := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "2001:db8:201::11/128", Table: 102},
+				{FwMark: "0x10", Table: 102, Family: "inet6"},
+			},
+			Routes: []RouteModel{
+				{To: "::/0", Via: "fe80::1", Dev: "eth2.104", Table: 102},
+				{To: "default", Via: "2001:db8:201::1", Src: "2001:db8:201::11", Table: 102},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject rules and routes mixing address families", func() {
+		config := &ConfigModel{
+			Rules: []RuleModel{
+				{From: "2001:db8:201::11/128", To: "10.0.0.0/8", Table: 102},
+				{From: "172.31.201.11/32", Table: 102, Family: "inet6"},
+				{Table: 102, Family: "ipx"},
+			},
+			Routes: []RouteModel{
+				{To: "::/0", Via: "172.31.201.1", Table: 102},
+				{To: "default", Via: "2001:db8:201::1", Src: "172.31.201.11", Table: 102},
+				{To: "2001:db8::/32", Table: 102, Nexthops: []NexthopModel{{Via: "fe80::1", Dev: "eth2.104"}, {Via: "172.31.201.1"}}},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.rules[0].to",
+			"spec.config.rules[1].from",
+			"spec.config.rules[2].family",
+			"spec.config.routes[0].via",
+			"spec.config.routes[1].src",
+			"spec.config.routes[2].nexthops[1].via",
+		}))
+	})
+
+	It("should accept addresses and address templates", func() {
+		config := &ConfigModel{
+			Addresses: []AddressModel{
+				{Dev: "eth2.104", Address: "172.31.201.11/24", Label: "eth2.104:sbr", NoPrefixRoute: true},
+				{Dev: "lo", Address: "2001:db8:ff::1/128", Scope: "global"},
+				{Dev: "gre1", Address: "10.0.0.1/32", Peer: "10.0.0.2/32"},
+				{Dev: "eth2.105", Address: `{{ index .Annotations "ipruler.pegah.tech/eth2.105" }}/24`},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed addresses", func() {
+		config := &ConfigModel{
+			Addresses: []AddressModel{
+				{Address: "172.31.201.11"},
+				{Dev: "gre1", Address: "10.0.0.1/32", Peer: "2001:db8::2", Scope: "universe"},
+				{Dev: "eth2.104", Address: "172.31.201.11/24", Label: "eth3:sbr"},
+				{Dev: "eth2.105", Address: "{{ .Name "},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.addresses[0].dev",
+			"spec.config.addresses[0].address",
+			"spec.config.addresses[1].peer",
+			"spec.config.addresses[1].scope",
+			"spec.config.addresses[2].label",
+			"spec.config.addresses[3].address",
+		}))
+	})
+
+	It("should accept links", func() {
+		config := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "bond0", Type: "bond", MTU: 9000, Bond: &BondModel{Mode: "802.3ad", Miimon: 100, Slaves: []string{"eth0", "eth1"}}},
+				{Name: "bond0.104", Type: "vlan", Vlan: &VlanModel{Link: "bond0", ID: 104}},
+				{Name: "br0", Type: "bridge", Bridge: &BridgeModel{Ports: []string{"eth2"}, STP: true}},
+				{Name: "anycast0", Type: "dummy"},
+				{Name: "macvlan0", Type: "macvlan", Macvlan: &MacvlanModel{Link: "eth3", Mode: "bridge"}},
+				{Name: "ipvlan0", Type: "ipvlan", Ipvlan: &IpvlanModel{Link: "eth3", Mode: "l3"}},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed links", func() {
+		config := &ConfigModel{
+			Vlans: []VlanModel{{Name: "eth2.104", Link: "eth2", ID: 104}},
+			Links: []LinkModel{
+				{Name: "eth2.104", Type: "dummy"},
+				{Name: "bond0", Type: "bond", Bond: &BondModel{Mode: "lacp", Slaves: []string{"eth0"}}, Bridge: &BridgeModel{}},
+				{Name: "bond1", Type: "bond", Bond: &BondModel{Slaves: []string{"eth0"}}},
+				{Name: "macvlan0", Type: "macvlan"},
+				{Name: "vlan0", Type: "vlan", Vlan: &VlanModel{Name: "vlan1", Link: "vlan0", ID: 5000}},
+				{Name: "tap0", Type: "tap"},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.links[0].name",
+			"spec.config.links[1].bridge",
+			"spec.config.links[1].bond.mode",
+			"spec.config.links[2].bond",
+			"spec.config.links[3].macvlan",
+			"spec.config.links[4].vlan.name",
+			"spec.config.links[4].vlan.id",
+			"spec.config.links[5].type",
+			"spec.config.links",
+		}))
+	})
+
+	It("should accept tunnels", func() {
+		config := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "vxlan100", Type: "vxlan", Vxlan: &VxlanModel{VNI: 100, Local: "172.31.201.11", Remote: "172.31.202.11", DstPort: 4789}},
+				{Name: "vxlan200", Type: "vxlan", Vxlan: &VxlanModel{VNI: 200, Group: "239.1.1.1", Dev: "eth2"}},
+				{Name: "gre1", Type: "gre", Gre: &TunnelModel{Local: "2001:db8::1", Remote: "2001:db8::2", TTL: 64}},
+				{Name: "ipip1", Type: "ipip", Ipip: &TunnelModel{Local: "172.31.201.11", Remote: "172.31.202.11"}},
+				{Name: "wg0", Type: "wireguard", Wireguard: &WireguardModel{
+					PrivateKeySecretRef: SecretKeyRef{Namespace: "kube-system", Name: "wg0", Key: "private-key"},
+					ListenPort:          51820,
+					Peers: []WireguardPeerModel{{
+						PublicKey:           "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=",
+						Endpoint:            "dc2.example.com:51820",
+						AllowedIPs:          []string{"10.20.0.0/16"},
+						PersistentKeepalive: 25,
+					}},
+				}},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed tunnels", func() {
+		config := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "vxlan100", Type: "vxlan", Vxlan: &VxlanModel{Remote: "172.31.202.11", Group: "239.1.1.1"}},
+				{Name: "vxlan200", Type: "vxlan", Vxlan: &VxlanModel{VNI: 200, Group: "172.31.202.11"}},
+				{Name: "gre1", Type: "gre", Gre: &TunnelModel{Local: "2001:db8::1", Remote: "172.31.202.11", TTL: 300}},
+				{Name: "ipip1", Type: "ipip", Ipip: &TunnelModel{Remote: "2001:db8::2"}},
+				{Name: "wg0", Type: "wireguard", Wireguard: &WireguardModel{
+					Peers: []WireguardPeerModel{{PublicKey: "key", Endpoint: "dc2.example.com", AllowedIPs: []string{"10.20.0.1"}}},
+				}},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.links[0].vxlan.vni",
+			"spec.config.links[0].vxlan.group",
+			"spec.config.links[1].vxlan.group",
+			"spec.config.links[2].gre.remote",
+			"spec.config.links[2].gre.ttl",
+			"spec.config.links[3].ipip.remote",
+			"spec.config.links[4].wireguard.private-key-secret-ref.namespace",
+			"spec.config.links[4].wireguard.private-key-secret-ref.name",
+			"spec.config.links[4].wireguard.private-key-secret-ref.key",
+			"spec.config.links[4].wireguard.peers[0].public-key",
+			"spec.config.links[4].wireguard.peers[0].endpoint",
+			"spec.config.links[4].wireguard.peers[0].allowed-ips[0]",
+		}))
+	})
+
+	It("should accept neighbors and FDB entries", func() {
+		config := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "vxlan100", Type: "vxlan", Vxlan: &VxlanModel{VNI: 100, DstPort: 4789}},
+			},
+			Neighbors: []NeighborModel{
+				{Dev: "eth2.104", IP: "172.31.201.50", LLAddr: "52:54:00:12:34:56"},
+				{Dev: "eth2.104", IP: "2001:db8:201::50", LLAddr: "52:54:00:12:34:56", State: "noarp"},
+			},
+			Fdb: []FdbModel{
+				{Dev: "vxlan100", LLAddr: "00:00:00:00:00:00", Dst: "172.31.202.11"},
+				{Dev: "vxlan100", LLAddr: "52:54:00:ab:cd:ef", Dst: "172.31.202.12", VNI: 100},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed neighbors and FDB entries", func() {
+		config := &ConfigModel{
+			Links: []LinkModel{
+				{Name: "dummy0", Type: "dummy"},
+			},
+			Neighbors: []NeighborModel{
+				{Dev: "eth2.104", IP: "172.31.201", LLAddr: "52:54:00:12:34"},
+				{IP: "172.31.201.50", LLAddr: "52:54:00:12:34:56", State: "failed"},
+			},
+			Fdb: []FdbModel{
+				{Dev: "dummy0", LLAddr: "00:00:00:00:00:00", Dst: "172.31.202.11"},
+				{Dev: "vxlan100", LLAddr: "52:54:00:ab:cd:ef", VNI: 1 << 24},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.neighbors[0].ip",
+			"spec.config.neighbors[0].lladdr",
+			"spec.config.neighbors[1].dev",
+			"spec.config.neighbors[1].state",
+			"spec.config.fdb[0].dev",
+			"spec.config.fdb[1].dst",
+			"spec.config.fdb[1].vni",
+		}))
+	})
+
+	It("should accept networking sysctls", func() {
+		config := &ConfigModel{
+			Settings: SettingsModel{Sysctls: map[string]string{
+				"net.ipv4.ip_forward":                 "1",
+				"net.ipv4.conf.all.rp_filter":         "2",
+				"net.ipv4.conf.eth2/104.rp_filter":    "2",
+				"net.ipv4.conf.eth2/104.arp_ignore":   "1",
+				"net.ipv4.conf.eth2/104.arp_announce": "2",
+				"net.ipv6.conf.eth2/104.accept_ra":    "0",
+			}},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject sysctls out of the allowlist", func() {
+		config := &ConfigModel{
+			Settings: SettingsModel{Sysctls: map[string]string{
+				"kernel.panic":                     "1",
+				"net.ipv4.conf.eth2.104.rp_filter": "2",
+				"net.ipv4.conf.eth2/104.rp_filter": "loose",
+			}},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.settings.sysctls[kernel.panic]",
+			"spec.config.settings.sysctls[net.ipv4.conf.eth2.104.rp_filter]",
+			"spec.config.settings.sysctls[net.ipv4.conf.eth2/104.rp_filter]",
+		}))
+	})
+
+	It("should filter out the sysctls out of the allowlist", func() {
+		config := &ConfigModel{
+			Settings: SettingsModel{Sysctls: map[string]string{
+				"kernel.panic":                     "1",
+				"net.ipv4.ip_forward":              "1",
+				"net.ipv4.conf.eth2/104.rp_filter": "loose",
+			}},
+		}
+		Expect(FilterSysctls(config)).To(Equal([]string{"kernel.panic", "net.ipv4.conf.eth2/104.rp_filter"}))
+		Expect(config.Settings.Sysctls).To(Equal(map[string]string{"net.ipv4.ip_forward": "1"}))
+	})
+
+	It("should accept VRFs", func() {
+		config := &ConfigModel{
+			Settings: SettingsModel{TableHardSync: []int{102}},
+			Vlans:    []VlanModel{{Name: "eth2.110", Link: "eth2", ID: 110}},
+			Vrfs: []VrfModel{
+				{Name: "storage", Table: 110, Interfaces: []string{"eth2.110"}},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.210.1", Table: 110},
+			},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(BeEmpty())
+	})
+
+	It("should reject malformed VRFs and hard synced VRF tables", func() {
+		config := &ConfigModel{
+			Settings: SettingsModel{TableHardSync: []int{102, 110}},
+			Vlans:    []VlanModel{{Name: "eth2.110", Link: "eth2", ID: 110}},
+			Vrfs: []VrfModel{
+				{Name: "storage", Table: 110, Interfaces: []string{"eth2.110"}},
+				{Name: "backup", Table: 110, Interfaces: []string{"eth2.110"}},
+				{Name: "eth2.110", Table: 254},
+				{Name: "mgmt"},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.vrfs[1].table",
+			"spec.config.vrfs[1].interfaces[0]",
+			"spec.config.vrfs[2].name",
+			"spec.config.vrfs[2].table",
+			"spec.config.vrfs[3].table",
+			"spec.config.settings.table-hard-sync[1]",
+		}))
+	})
+
+	It("should reject VRF tables hard synced by another config", func() {
+		config := &ConfigModel{
+			Vrfs: []VrfModel{
+				{Name: "storage", Table: 110, Interfaces: []string{"eth2.110"}},
+			},
+			Routes: []RouteModel{
+				{To: "default", Via: "172.31.210.1", Table: 110},
+				{To: "default", Via: "172.31.201.1", Table: 102},
+				{To: "10.110.0.0/16", Via: "172.31.210.1", TableName: "storage"},
+				{To: "10.102.0.0/16", Via: "172.31.201.1", TableName: "isp2"},
+				{To: "10.0.0.0/8", Via: "172.31.201.1", TableName: "unknown"},
+			},
+		}
+		tables := map[string]int{"storage": 110, "isp2": 102}
+		errs := ValidateVrfRoutes(config, []int{102, 110}, tables, fldPath)
+		fields := []string{}
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(Equal([]string{
+			"spec.config.vrfs[0].table",
+			"spec.config.routes[0].table",
+			"spec.config.routes[2].table-name",
+		}))
+
+		other := &ConfigModel{Settings: SettingsModel{TableHardSync: []int{110}}}
+		Expect(ValidateHardSyncedTables(other, VrfTables(config), fldPath)).To(HaveLen(1))
+	})
+
+	It("should reject a route without a gateway and a device", func() {
+		config := &ConfigModel{
+			Routes: []RouteModel{{To: "10.0.0.0/8"}},
+		}
+		Expect(ValidateConfigModel(config, fldPath)).To(HaveLen(1))
+	})
+
+	It("should reject out of range VLAN IDs", func() {
+		config := &ConfigModel{
+			Vlans: []VlanModel{
+				{Name: "eth2.0", Link: "eth2", ID: 0},
+				{Name: "eth2.4095", Link: "eth2", ID: 4095},
+			},
+		}
+		errs := ValidateConfigModel(config, fldPath)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("spec.config.vlans[0].id"))
+		Expect(errs[1].Field).To(Equal("spec.config.vlans[1].id"))
+	})
+})
@@ -0,0 +1,548 @@
+//go:build !ignore_autogenerated
+
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package models
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddressModel) DeepCopyInto(out *AddressModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddressModel.
+func (in *AddressModel) DeepCopy() *AddressModel {
+	if in == nil {
+		return nil
+	}
+	out := new(AddressModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BondModel) DeepCopyInto(out *BondModel) {
+	*out = *in
+	if in.Slaves != nil {
+		in, out := &in.Slaves, &out.Slaves
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BondModel.
+func (in *BondModel) DeepCopy() *BondModel {
+	if in == nil {
+		return nil
+	}
+	out := new(BondModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BridgeModel) DeepCopyInto(out *BridgeModel) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BridgeModel.
+func (in *BridgeModel) DeepCopy() *BridgeModel {
+	if in == nil {
+		return nil
+	}
+	out := new(BridgeModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigModel) DeepCopyInto(out *ConfigModel) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]RuleModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Settings.DeepCopyInto(&out.Settings)
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]RouteModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Vlans != nil {
+		in, out := &in.Vlans, &out.Vlans
+		*out = make([]VlanModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]LinkModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Vrfs != nil {
+		in, out := &in.Vrfs, &out.Vrfs
+		*out = make([]VrfModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Neighbors != nil {
+		in, out := &in.Neighbors, &out.Neighbors
+		*out = make([]NeighborModel, len(*in))
+		copy(*out, *in)
+	}
+	if in.Fdb != nil {
+		in, out := &in.Fdb, &out.Fdb
+		*out = make([]FdbModel, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]TableModel, len(*in))
+		copy(*out, *in)
+	}
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]AddressModel, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigModel.
+func (in *ConfigModel) DeepCopy() *ConfigModel {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExcludeModel) DeepCopyInto(out *ExcludeModel) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]RuleModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]RouteModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Vlans != nil {
+		in, out := &in.Vlans, &out.Vlans
+		*out = make([]VlanModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExcludeModel.
+func (in *ExcludeModel) DeepCopy() *ExcludeModel {
+	if in == nil {
+		return nil
+	}
+	out := new(ExcludeModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FdbModel) DeepCopyInto(out *FdbModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FdbModel.
+func (in *FdbModel) DeepCopy() *FdbModel {
+	if in == nil {
+		return nil
+	}
+	out := new(FdbModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IpvlanModel) DeepCopyInto(out *IpvlanModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IpvlanModel.
+func (in *IpvlanModel) DeepCopy() *IpvlanModel {
+	if in == nil {
+		return nil
+	}
+	out := new(IpvlanModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LinkModel) DeepCopyInto(out *LinkModel) {
+	*out = *in
+	if in.Bond != nil {
+		in, out := &in.Bond, &out.Bond
+		*out = new(BondModel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bridge != nil {
+		in, out := &in.Bridge, &out.Bridge
+		*out = new(BridgeModel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Macvlan != nil {
+		in, out := &in.Macvlan, &out.Macvlan
+		*out = new(MacvlanModel)
+		**out = **in
+	}
+	if in.Ipvlan != nil {
+		in, out := &in.Ipvlan, &out.Ipvlan
+		*out = new(IpvlanModel)
+		**out = **in
+	}
+	if in.Vlan != nil {
+		in, out := &in.Vlan, &out.Vlan
+		*out = new(VlanModel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Vxlan != nil {
+		in, out := &in.Vxlan, &out.Vxlan
+		*out = new(VxlanModel)
+		**out = **in
+	}
+	if in.Gre != nil {
+		in, out := &in.Gre, &out.Gre
+		*out = new(TunnelModel)
+		**out = **in
+	}
+	if in.Ipip != nil {
+		in, out := &in.Ipip, &out.Ipip
+		*out = new(TunnelModel)
+		**out = **in
+	}
+	if in.Wireguard != nil {
+		in, out := &in.Wireguard, &out.Wireguard
+		*out = new(WireguardModel)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LinkModel.
+func (in *LinkModel) DeepCopy() *LinkModel {
+	if in == nil {
+		return nil
+	}
+	out := new(LinkModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MacvlanModel) DeepCopyInto(out *MacvlanModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MacvlanModel.
+func (in *MacvlanModel) DeepCopy() *MacvlanModel {
+	if in == nil {
+		return nil
+	}
+	out := new(MacvlanModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeighborModel) DeepCopyInto(out *NeighborModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeighborModel.
+func (in *NeighborModel) DeepCopy() *NeighborModel {
+	if in == nil {
+		return nil
+	}
+	out := new(NeighborModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NexthopModel) DeepCopyInto(out *NexthopModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NexthopModel.
+func (in *NexthopModel) DeepCopy() *NexthopModel {
+	if in == nil {
+		return nil
+	}
+	out := new(NexthopModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteModel) DeepCopyInto(out *RouteModel) {
+	*out = *in
+	if in.Nexthops != nil {
+		in, out := &in.Nexthops, &out.Nexthops
+		*out = make([]NexthopModel, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteModel.
+func (in *RouteModel) DeepCopy() *RouteModel {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleModel) DeepCopyInto(out *RuleModel) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleModel.
+func (in *RuleModel) DeepCopy() *RuleModel {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyRef) DeepCopyInto(out *SecretKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyRef.
+func (in *SecretKeyRef) DeepCopy() *SecretKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SettingsModel) DeepCopyInto(out *SettingsModel) {
+	*out = *in
+	if in.TableHardSync != nil {
+		in, out := &in.TableHardSync, &out.TableHardSync
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SettingsModel.
+func (in *SettingsModel) DeepCopy() *SettingsModel {
+	if in == nil {
+		return nil
+	}
+	out := new(SettingsModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TableModel) DeepCopyInto(out *TableModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TableModel.
+func (in *TableModel) DeepCopy() *TableModel {
+	if in == nil {
+		return nil
+	}
+	out := new(TableModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelModel) DeepCopyInto(out *TunnelModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelModel.
+func (in *TunnelModel) DeepCopy() *TunnelModel {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VlanModel) DeepCopyInto(out *VlanModel) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VlanModel.
+func (in *VlanModel) DeepCopy() *VlanModel {
+	if in == nil {
+		return nil
+	}
+	out := new(VlanModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VrfModel) DeepCopyInto(out *VrfModel) {
+	*out = *in
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VrfModel.
+func (in *VrfModel) DeepCopy() *VrfModel {
+	if in == nil {
+		return nil
+	}
+	out := new(VrfModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VxlanModel) DeepCopyInto(out *VxlanModel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VxlanModel.
+func (in *VxlanModel) DeepCopy() *VxlanModel {
+	if in == nil {
+		return nil
+	}
+	out := new(VxlanModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WireguardModel) DeepCopyInto(out *WireguardModel) {
+	*out = *in
+	out.PrivateKeySecretRef = in.PrivateKeySecretRef
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]WireguardPeerModel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WireguardModel.
+func (in *WireguardModel) DeepCopy() *WireguardModel {
+	if in == nil {
+		return nil
+	}
+	out := new(WireguardModel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WireguardPeerModel) DeepCopyInto(out *WireguardPeerModel) {
+	*out = *in
+	if in.AllowedIPs != nil {
+		in, out := &in.AllowedIPs, &out.AllowedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WireguardPeerModel.
+func (in *WireguardPeerModel) DeepCopy() *WireguardPeerModel {
+	if in == nil {
+		return nil
+	}
+	out := new(WireguardPeerModel)
+	in.DeepCopyInto(out)
+	return out
+}
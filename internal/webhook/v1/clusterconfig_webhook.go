@@ -0,0 +1,147 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// log is for logging in this package.
+var clusterconfiglog = logf.Log.WithName("clusterconfig-resource")
+
+// SetupClusterConfigWebhookWithManager registers the webhook for ClusterConfig in the manager.
+// When singleton is set, creating a second ClusterConfig in the cluster is rejected.
+func SetupClusterConfigWebhookWithManager(mgr ctrl.Manager, singleton bool) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&iprulerv1.ClusterConfig{}).
+		WithValidator(&ClusterConfigCustomValidator{
+			Client:    mgr.GetClient(),
+			Singleton: singleton,
+		}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-ipruler-pegah-tech-v1-clusterconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=ipruler.pegah.tech,resources=clusterconfigs,verbs=create;update,versions=v1,name=vclusterconfig-v1.kb.io,admissionReviewVersions=v1
+
+// ClusterConfigCustomValidator validates the ClusterConfig resources when they are created or updated.
+type ClusterConfigCustomValidator struct {
+	Client    client.Client
+	Singleton bool
+}
+
+var _ webhook.CustomValidator = &ClusterConfigCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type ClusterConfig.
+func (v *ClusterConfigCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clusterConfig, ok := obj.(*iprulerv1.ClusterConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterConfig object but got %T", obj)
+	}
+	clusterconfiglog.Info("Validation for ClusterConfig upon creation", "name", clusterConfig.GetName())
+
+	allErrs := models.ValidateConfigModel(&clusterConfig.Spec.Config, field.NewPath("spec", "config"))
+	allErrs = append(allErrs, validateRolloutStrategy(clusterConfig.Spec.RolloutStrategy, field.NewPath("spec", "rolloutStrategy"))...)
+	mergedErrs, err := v.validateMergedTables(ctx, clusterConfig)
+	if err != nil {
+		return nil, err
+	}
+	allErrs = append(allErrs, mergedErrs...)
+
+	if v.Singleton {
+		clusterConfigList := &iprulerv1.ClusterConfigList{}
+		if err := v.Client.List(ctx, clusterConfigList); err != nil {
+			return nil, err
+		}
+		for _, existing := range clusterConfigList.Items {
+			if existing.Name != clusterConfig.Name {
+				allErrs = append(allErrs, field.Forbidden(field.NewPath("metadata", "name"),
+					fmt.Sprintf("ClusterConfig %q already exists and only a single ClusterConfig is allowed in the cluster", existing.Name)))
+				break
+			}
+		}
+	}
+
+	return nil, invalidClusterConfig(clusterConfig, allErrs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type ClusterConfig.
+func (v *ClusterConfigCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	clusterConfig, ok := newObj.(*iprulerv1.ClusterConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterConfig object for the newObj but got %T", newObj)
+	}
+	clusterconfiglog.Info("Validation for ClusterConfig upon update", "name", clusterConfig.GetName())
+
+	allErrs := models.ValidateConfigModel(&clusterConfig.Spec.Config, field.NewPath("spec", "config"))
+	allErrs = append(allErrs, validateRolloutStrategy(clusterConfig.Spec.RolloutStrategy, field.NewPath("spec", "rolloutStrategy"))...)
+	mergedErrs, err := v.validateMergedTables(ctx, clusterConfig)
+	if err != nil {
+		return nil, err
+	}
+	allErrs = append(allErrs, mergedErrs...)
+
+	return nil, invalidClusterConfig(clusterConfig, allErrs)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type ClusterConfig.
+func (v *ClusterConfigCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateMergedTables checks the tables of the ClusterConfig against the configs it is merged with,
+// i.e. every other ClusterConfig and every NodeConfig.
+func (v *ClusterConfigCustomValidator) validateMergedTables(ctx context.Context, clusterConfig *iprulerv1.ClusterConfig) (field.ErrorList, error) {
+	clusterConfigList := &iprulerv1.ClusterConfigList{}
+	if err := v.Client.List(ctx, clusterConfigList); err != nil {
+		return nil, err
+	}
+	nodeConfigList := &iprulerv1.NodeConfigList{}
+	if err := v.Client.List(ctx, nodeConfigList); err != nil {
+		return nil, err
+	}
+
+	var others []*models.ConfigModel
+	for i := range clusterConfigList.Items {
+		if clusterConfigList.Items[i].Name != clusterConfig.Name {
+			others = append(others, &clusterConfigList.Items[i].Spec.Config)
+		}
+	}
+	for i := range nodeConfigList.Items {
+		others = append(others, &nodeConfigList.Items[i].Spec.Config)
+	}
+
+	return validateMergedTables(&clusterConfig.Spec.Config, others, field.NewPath("spec", "config")), nil
+}
+
+func invalidClusterConfig(clusterConfig *iprulerv1.ClusterConfig, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(iprulerv1.GroupVersion.WithKind("ClusterConfig").GroupKind(), clusterConfig.Name, allErrs)
+}
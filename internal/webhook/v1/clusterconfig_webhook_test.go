@@ -0,0 +1,105 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+var _ = Describe("ClusterConfig Webhook", func() {
+	ctx := context.Background()
+	clusterConfig := func(name string, config models.ConfigModel) *iprulerv1.ClusterConfig {
+		return &iprulerv1.ClusterConfig{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: iprulerv1.ClusterConfigSpec{Config: config}}
+	}
+	nodeConfig := func(name string, config models.ConfigModel) *iprulerv1.NodeConfig {
+		return &iprulerv1.NodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: iprulerv1.NodeConfigSpec{Config: config}}
+	}
+
+	It("should accept a valid ClusterConfig", func() {
+		validator := &ClusterConfigCustomValidator{Client: newFakeClient()}
+		obj := clusterConfig("cluster", models.ConfigModel{
+			Tables: []models.TableModel{{Name: "isp", ID: 102}},
+			Rules:  []models.RuleModel{{From: "172.31.201.11/32", TableName: "isp"}},
+			Routes: []models.RouteModel{{To: "default", Via: "172.31.201.1", Table: 102}},
+		})
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = validator.ValidateUpdate(ctx, obj, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject an invalid config on creation and update", func() {
+		validator := &ClusterConfigCustomValidator{Client: newFakeClient()}
+		obj := clusterConfig("cluster", models.ConfigModel{
+			Rules: []models.RuleModel{{From: "172.31.201.300/32", Table: 102}},
+		})
+		obj.Spec.RolloutStrategy = &iprulerv1.RolloutStrategy{PauseBetweenBatches: &metav1.Duration{Duration: -1}}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(invalidFields(err)).To(Equal([]string{"spec.config.rules[0].from", "spec.rolloutStrategy.pauseBetweenBatches"}))
+		_, err = validator.ValidateUpdate(ctx, obj, obj)
+		Expect(invalidFields(err)).To(Equal([]string{"spec.config.rules[0].from", "spec.rolloutStrategy.pauseBetweenBatches"}))
+	})
+
+	It("should reject a second ClusterConfig when singleton", func() {
+		existing := clusterConfig("platform", models.ConfigModel{})
+		obj := clusterConfig("security", models.ConfigModel{})
+
+		validator := &ClusterConfigCustomValidator{Client: newFakeClient(existing), Singleton: true}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(invalidFields(err)).To(Equal([]string{"metadata.name"}))
+		// an existing ClusterConfig can still be updated
+		_, err = validator.ValidateUpdate(ctx, existing, existing)
+		Expect(err).NotTo(HaveOccurred())
+
+		validator.Singleton = false
+		_, err = validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should check the tables against the configs it is merged with", func() {
+		validator := &ClusterConfigCustomValidator{Client: newFakeClient(
+			clusterConfig("platform", models.ConfigModel{Tables: []models.TableModel{{Name: "isp", ID: 102}}}),
+			nodeConfig("storage", models.ConfigModel{Vrfs: []models.VrfModel{{Name: "storage", Table: 110}}}),
+		)}
+		obj := clusterConfig("security", models.ConfigModel{
+			Settings: models.SettingsModel{TableHardSync: []int{110}},
+			Tables:   []models.TableModel{{Name: "isp", ID: 103}},
+			Rules:    []models.RuleModel{{From: "172.31.201.11/32", TableName: "isp2"}},
+		})
+		_, err := validator.ValidateUpdate(ctx, obj, obj)
+		Expect(invalidFields(err)).To(Equal([]string{
+			"spec.config.settings.table-hard-sync[0]",
+			"spec.config.tables[0].id",
+			"spec.config.rules[0].table-name",
+		}))
+	})
+
+	It("should reject other objects", func() {
+		validator := &ClusterConfigCustomValidator{Client: newFakeClient()}
+		_, err := validator.ValidateCreate(ctx, nodeConfig("storage", models.ConfigModel{}))
+		Expect(err).To(HaveOccurred())
+		_, err = validator.ValidateUpdate(ctx, nil, nodeConfig("storage", models.ConfigModel{}))
+		Expect(err).To(HaveOccurred())
+	})
+})
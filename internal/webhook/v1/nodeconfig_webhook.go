@@ -0,0 +1,157 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+// log is for logging in this package.
+var nodeconfiglog = logf.Log.WithName("nodeconfig-resource")
+
+// SetupNodeConfigWebhookWithManager registers the webhook for NodeConfig in the manager.
+func SetupNodeConfigWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&iprulerv1.NodeConfig{}).
+		WithValidator(&NodeConfigCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-ipruler-pegah-tech-v1-nodeconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=ipruler.pegah.tech,resources=nodeconfigs,verbs=create;update,versions=v1,name=vnodeconfig-v1.kb.io,admissionReviewVersions=v1
+
+// NodeConfigCustomValidator validates the NodeConfig resources when they are created or updated.
+type NodeConfigCustomValidator struct {
+	Client client.Client
+}
+
+var _ webhook.CustomValidator = &NodeConfigCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type NodeConfig.
+func (v *NodeConfigCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	nodeConfig, ok := obj.(*iprulerv1.NodeConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a NodeConfig object but got %T", obj)
+	}
+	nodeconfiglog.Info("Validation for NodeConfig upon creation", "name", nodeConfig.GetName())
+
+	return nil, v.validateNodeConfig(ctx, nodeConfig)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type NodeConfig.
+func (v *NodeConfigCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	nodeConfig, ok := newObj.(*iprulerv1.NodeConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a NodeConfig object for the newObj but got %T", newObj)
+	}
+	nodeconfiglog.Info("Validation for NodeConfig upon update", "name", nodeConfig.GetName())
+
+	return nil, v.validateNodeConfig(ctx, nodeConfig)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type NodeConfig.
+func (v *NodeConfigCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *NodeConfigCustomValidator) validateNodeConfig(ctx context.Context, nodeConfig *iprulerv1.NodeConfig) error {
+	// the config of a NodeConfig is merged with the configs of every ClusterConfig
+	clusterConfigList := &iprulerv1.ClusterConfigList{}
+	if err := v.Client.List(ctx, clusterConfigList); err != nil {
+		return err
+	}
+	var clusterConfigs []*models.ConfigModel
+	for i := range clusterConfigList.Items {
+		clusterConfigs = append(clusterConfigs, &clusterConfigList.Items[i].Spec.Config)
+	}
+
+	allErrs := models.ValidateConfigModel(&nodeConfig.Spec.Config, field.NewPath("spec", "config"))
+	allErrs = append(allErrs, validateMergedTables(&nodeConfig.Spec.Config, clusterConfigs, field.NewPath("spec", "config"))...)
+	allErrs = append(allErrs, models.ValidateExclude(nodeConfig.Spec.Exclude, field.NewPath("spec", "exclude"))...)
+	if len(nodeConfig.Spec.Config.Tables) > 0 {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "config", "tables"), "may only be defined by the ClusterConfigs"))
+	}
+	allErrs = append(allErrs, validateRolloutStrategy(nodeConfig.Spec.RolloutStrategy, field.NewPath("spec", "rolloutStrategy"))...)
+	if nodeConfig.Spec.ResyncInterval != nil && nodeConfig.Spec.ResyncInterval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "resyncInterval"), nodeConfig.Spec.ResyncInterval.Duration.String(), "must not be negative"))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(iprulerv1.GroupVersion.WithKind("NodeConfig").GroupKind(), nodeConfig.Name, allErrs)
+}
+
+// validateMergedTables checks that the VRFs of the config don't collide with the table-hard-sync of the configs it is
+// merged with, and the other way around. It also checks the named tables against the ones of these configs.
+func validateMergedTables(config *models.ConfigModel, others []*models.ConfigModel, fldPath *field.Path) field.ErrorList {
+	var hardSyncedTables []int
+	vrfTables := map[int]string{}
+	for _, other := range others {
+		hardSyncedTables = append(hardSyncedTables, other.Settings.TableHardSync...)
+		for _, table := range other.Tables {
+			if table.HardSync {
+				hardSyncedTables = append(hardSyncedTables, table.ID)
+			}
+		}
+		for table, vrf := range models.VrfTables(other) {
+			vrfTables[table] = vrf
+		}
+	}
+	tables := models.TableIDs(append([]*models.ConfigModel{config}, others...)...)
+
+	allErrs := models.ValidateVrfRoutes(config, hardSyncedTables, tables, fldPath)
+	allErrs = append(allErrs, models.ValidateHardSyncedTables(config, vrfTables, fldPath)...)
+	allErrs = append(allErrs, models.ValidateTableIDs(config, others, fldPath)...)
+	allErrs = append(allErrs, models.ValidateTableNames(config, tables, fldPath)...)
+	return allErrs
+}
+
+// validateRolloutStrategy checks that the batches of the rollout strategy are not empty.
+func validateRolloutStrategy(strategy *iprulerv1.RolloutStrategy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if strategy == nil {
+		return allErrs
+	}
+
+	if strategy.MaxUnavailable != nil {
+		maxUnavailablePath := fldPath.Child("maxUnavailable")
+		// scaling 100 nodes gives the percentage itself
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(strategy.MaxUnavailable, 100, true)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(maxUnavailablePath, strategy.MaxUnavailable.String(), err.Error()))
+		} else if maxUnavailable < 1 {
+			allErrs = append(allErrs, field.Invalid(maxUnavailablePath, strategy.MaxUnavailable.String(), "must be greater than zero"))
+		}
+	}
+	if strategy.PauseBetweenBatches != nil && strategy.PauseBetweenBatches.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("pauseBetweenBatches"), strategy.PauseBetweenBatches.Duration.String(), "must not be negative"))
+	}
+
+	return allErrs
+}
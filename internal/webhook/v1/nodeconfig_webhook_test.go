@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+	"github.com/plutocholia/ipruler-operator/internal/models"
+)
+
+var _ = Describe("NodeConfig Webhook", func() {
+	ctx := context.Background()
+	cluster := &iprulerv1.ClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec: iprulerv1.ClusterConfigSpec{Config: models.ConfigModel{
+			Settings: models.SettingsModel{TableHardSync: []int{110}},
+			Tables:   []models.TableModel{{Name: "isp", ID: 102}},
+		}},
+	}
+	nodeConfig := func(config models.ConfigModel) *iprulerv1.NodeConfig {
+		return &iprulerv1.NodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "storage"}, Spec: iprulerv1.NodeConfigSpec{Config: config}}
+	}
+
+	It("should accept a NodeConfig referring to the tables of the ClusterConfigs", func() {
+		validator := &NodeConfigCustomValidator{Client: newFakeClient(cluster)}
+		obj := nodeConfig(models.ConfigModel{
+			Routes: []models.RouteModel{{To: "default", Via: "172.31.201.1", TableName: "isp"}},
+		})
+		obj.Spec.Exclude = &models.ExcludeModel{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"interface": "eth2"}}}
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = validator.ValidateUpdate(ctx, obj, obj)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject a NodeConfig colliding with the ClusterConfigs", func() {
+		validator := &NodeConfigCustomValidator{Client: newFakeClient(cluster)}
+		obj := nodeConfig(models.ConfigModel{
+			Tables: []models.TableModel{{Name: "isp2", ID: 103}},
+			Routes: []models.RouteModel{{To: "default", Via: "172.31.201.1", TableName: "isp3"}},
+			Vrfs:   []models.VrfModel{{Name: "storage", Table: 110}},
+		})
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(invalidFields(err)).To(Equal([]string{
+			"spec.config.vrfs[0].table",
+			"spec.config.routes[0].table-name",
+			"spec.config.tables",
+		}))
+	})
+
+	It("should reject routes referring by name to a VRF table hard synced by the ClusterConfigs", func() {
+		hardSynced := &iprulerv1.ClusterConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "hard-synced"},
+			Spec: iprulerv1.ClusterConfigSpec{Config: models.ConfigModel{
+				Tables: []models.TableModel{{Name: "storage", ID: 120, HardSync: true}},
+			}},
+		}
+		validator := &NodeConfigCustomValidator{Client: newFakeClient(hardSynced)}
+		obj := nodeConfig(models.ConfigModel{
+			Vrfs:   []models.VrfModel{{Name: "storage", Table: 120, Interfaces: []string{"eth2.120"}}},
+			Routes: []models.RouteModel{{To: "default", Via: "172.31.220.1", TableName: "storage"}},
+		})
+		_, err := validator.ValidateCreate(ctx, obj)
+		Expect(invalidFields(err)).To(Equal([]string{
+			"spec.config.vrfs[0].table",
+			"spec.config.routes[0].table-name",
+		}))
+	})
+
+	It("should reject an invalid exclude block, rollout strategy and resync interval", func() {
+		validator := &NodeConfigCustomValidator{Client: newFakeClient(cluster)}
+		obj := nodeConfig(models.ConfigModel{})
+		obj.Spec.Exclude = &models.ExcludeModel{
+			Routes:   []models.RouteModel{{Table: 102}},
+			Selector: &metav1.LabelSelector{},
+		}
+		obj.Spec.RolloutStrategy = &iprulerv1.RolloutStrategy{PauseBetweenBatches: &metav1.Duration{Duration: -1}}
+		obj.Spec.ResyncInterval = &metav1.Duration{Duration: -1}
+		_, err := validator.ValidateUpdate(ctx, obj, obj)
+		Expect(invalidFields(err)).To(Equal([]string{
+			"spec.exclude.routes[0].to",
+			"spec.exclude.selector",
+			"spec.rolloutStrategy.pauseBetweenBatches",
+			"spec.resyncInterval",
+		}))
+	})
+
+	It("should reject other objects", func() {
+		validator := &NodeConfigCustomValidator{Client: newFakeClient()}
+		_, err := validator.ValidateCreate(ctx, cluster)
+		Expect(err).To(HaveOccurred())
+		_, err = validator.ValidateUpdate(ctx, nil, cluster)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,56 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	iprulerv1 "github.com/plutocholia/ipruler-operator/api/v1"
+)
+
+// The validators only list ClusterConfigs and NodeConfigs, so they are tested against a fake client
+// rather than an API server.
+
+func TestWebhooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Webhook Suite")
+}
+
+// newFakeClient returns a client knowing about the given objects.
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(iprulerv1.AddToScheme(scheme)).To(Succeed())
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+// invalidFields returns the fields an Invalid error of the API server complains about.
+func invalidFields(err error) []string {
+	Expect(apierrors.IsInvalid(err)).To(BeTrue(), "expected an Invalid error, got %v", err)
+	fields := []string{}
+	for _, cause := range err.(apierrors.APIStatus).Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}